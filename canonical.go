@@ -0,0 +1,103 @@
+package pot
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+)
+
+// Format POT text in canonical form.
+//
+// The canonical form has one root level value per line, each followed by a
+// new-line, with dictionaries and lists on a single line. Tokens are separated
+// by single spaces and strings are quoted and escaped only as needed, the same
+// way String.String does. Documents that differ only in space, comments,
+// quoting or escaping have the same canonical form. The order of dictionary
+// entries is kept as it's significant when keys are duplicated.
+//
+// Returns the canonical text or the first parse error.
+func Canonical(pot []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := Walk(pot, &canonicalHandler{w: &buf}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Get the SHA-256 hash of the canonical form of POT text.
+// Returns the hash or the first parse error.
+func Hash(pot []byte) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	h := sha256.New()
+	if err := Walk(pot, &canonicalHandler{w: h}); err != nil {
+		return sum, err
+	}
+	h.Sum(sum[:0])
+	return sum, nil
+}
+
+// Check if two POT texts have the same canonical form.
+// Returns the result or the first parse error of either text.
+func Equal(a, b []byte) (bool, error) {
+	ca, err := Canonical(a)
+	if err != nil {
+		return false, err
+	}
+	cb, err := Canonical(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(ca, cb), nil
+}
+
+// Handler writing the canonical form of the walked text.
+type canonicalHandler struct {
+	w     io.Writer
+	depth int // Nesting depth of dictionaries and lists.
+}
+
+func (h *canonicalHandler) StartDict(location Location) error {
+	return h.write("{ ", 1)
+}
+
+func (h *canonicalHandler) EndDict(location Location) error {
+	return h.writeValue("}", -1)
+}
+
+func (h *canonicalHandler) StartList(location Location) error {
+	return h.write("[ ", 1)
+}
+
+func (h *canonicalHandler) EndList(location Location) error {
+	return h.writeValue("]", -1)
+}
+
+func (h *canonicalHandler) Key(key []byte, location Location) error {
+	if err := h.write(string(key), 0); err != nil {
+		return err
+	}
+	return h.write(": ", 0)
+}
+
+func (h *canonicalHandler) String(value []byte, location Location) error {
+	return h.writeValue(formatString(value), 0)
+}
+
+// Write s and adjust the nesting depth by delta.
+func (h *canonicalHandler) write(s string, delta int) error {
+	h.depth += delta
+	_, err := io.WriteString(h.w, s)
+	return err
+}
+
+// Write s ending a value and adjust the nesting depth by delta. The value is
+// followed by a new-line at the root level and by a space otherwise.
+func (h *canonicalHandler) writeValue(s string, delta int) error {
+	if err := h.write(s, delta); err != nil {
+		return err
+	}
+	if h.depth == 0 {
+		return h.write("\n", 0)
+	}
+	return h.write(" ", 0)
+}
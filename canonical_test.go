@@ -0,0 +1,88 @@
+package pot
+
+import (
+	"fmt"
+	"testing"
+)
+
+func ExampleCanonical() {
+	buf, err := Canonical([]byte(`
+{
+    name:  "frontend"   # Service name.
+    owner: web\ team
+    ports: [ 80 443 ]
+}
+"single value"`))
+	if err != nil {
+		fmt.Println(err)
+	}
+	fmt.Print(string(buf))
+	// Output:
+	// { name: frontend owner: "web team" ports: [ 80 443 ] }
+	// "single value"
+}
+
+func ExampleEqual() {
+	equal, err := Equal([]byte(`{ a: "b c" d: [ e ] }`), []byte("{\n    a: b\\ c\n    d: [ \"e\" ] # Comment.\n}"))
+	fmt.Println(equal, err)
+	// Output:
+	// true <nil>
+}
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		pot, canonical string
+	}{
+		{"", ""},
+		{" # comment\n", ""},
+		{"{}[]\"\"", "{ }\n[ ]\n\"\"\n"},
+		{"{ a: { b: [ [ c ] { } ] } }", "{ a: { b: [ [ c ] { } ] } }\n"},
+		{"\"a\\tb\" \"\\\"\" a\\:b", "a\\tb\n\\\"\n\"a:b\"\n"},
+	}
+	for _, test := range tests {
+		buf, err := Canonical([]byte(test.pot))
+		if err != nil {
+			t.Errorf("Canonical(%q) error: %s", test.pot, err)
+		} else if string(buf) != test.canonical {
+			t.Errorf("Canonical(%q) = %q want %q", test.pot, buf, test.canonical)
+		}
+		// The canonical form must be stable and match the document model.
+		if again, _ := Canonical(buf); string(again) != string(buf) {
+			t.Errorf("Canonical(%q) = %q want %q", buf, again, buf)
+		}
+		nodes, _ := Parse([]byte(test.pot))
+		s := ""
+		for _, node := range nodes {
+			s += node.String() + "\n"
+		}
+		if s != test.canonical {
+			t.Errorf("Parse(%q) formatted as %q want %q", test.pot, s, test.canonical)
+		}
+	}
+
+	if _, err := Canonical([]byte("{ a }")); err == nil {
+		t.Errorf("Canonical() of invalid text succeeded")
+	}
+}
+
+func TestHash(t *testing.T) {
+	a, err := Hash([]byte("{ a: b }"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := Hash([]byte("{\n    a: \"b\"\n}\n")); a != b {
+		t.Errorf("hashes of equivalent texts differ")
+	}
+	if b, _ := Hash([]byte("{ a: c }")); a == b {
+		t.Errorf("hashes of different texts are equal")
+	}
+	if b, _ := Hash([]byte("{ a: b } {}")); a == b {
+		t.Errorf("hashes of different texts are equal")
+	}
+	if _, err = Hash([]byte("[")); err == nil {
+		t.Errorf("Hash() of invalid text succeeded")
+	}
+	if equal, err := Equal([]byte("a"), []byte("[")); equal || err == nil {
+		t.Errorf("Equal() with invalid text = %v, %v", equal, err)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/johan-bolmsjo/pot"
+	"github.com/johan-bolmsjo/pot/internal/cli"
+)
+
+const usage = `Usage: pot-diff [flags] old new
+
+Compare two POT files structurally and print the values that were added,
+removed or changed, prefixed with their locations in the old and new file.
+Differences in space, comments, quoting and the order of dictionary entries
+with different keys are ignored. The printed paths select the values with
+pot-query. A file named "-" is read from stdin.
+
+The exit status is 0 if the files are equal, 1 if they differ and 2 if an
+error occurred.
+
+Flags:
+`
+
+func main() {
+	quiet := flag.Bool("q", false, "only report whether the files differ")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	oldFile, newFile := flag.Arg(0), flag.Arg(1)
+	changes := pot.Diff(cli.ParseFile(oldFile), cli.ParseFile(newFile))
+	if len(changes) == 0 {
+		return
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	if *quiet {
+		fmt.Fprintf(out, "Files %s and %s differ\n", oldFile, newFile)
+	} else {
+		for _, change := range changes {
+			fmt.Fprintf(out, "%s:%s %s:%s: %s\n", oldFile, change.OldLocation, newFile, change.NewLocation, &change)
+		}
+	}
+	if err := out.Flush(); err != nil {
+		cli.Fatalf("Failed to write to stdout, %s\n", err)
+	}
+	os.Exit(1)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/johan-bolmsjo/pot"
+	"github.com/johan-bolmsjo/pot/internal/cli"
+)
+
+const usage = `Usage: pot-merge [flags] base ours theirs
+
+Merge the changes made to the POT files ours and theirs since their common
+ancestor base and write the result to ours. Values changed differently in ours
+and theirs are written between conflict markers.
+
+Ours is left untouched if the result equals it, and the text of theirs is used
+if the result equals theirs, keeping their comments and layout. Otherwise the
+result is pretty printed and comments are not kept. This happens when both ours
+and theirs changed values, not only comments or layout.
+
+The exit status is 0 if the merge was clean, 1 if there were conflicts and 2 if
+an error occurred.
+
+To use pot-merge as a git merge driver, add the driver to the git config:
+
+	[merge "pot"]
+		name = POT merge driver
+		driver = pot-merge %O %A %B
+
+and assign it to POT files in .gitattributes:
+
+	*.pot merge=pot
+
+Flags:
+`
+
+// Printer used to format merged files.
+var printer = pot.Printer{AlignKeys: true, FinalNewline: true}
+
+func main() {
+	stdout := flag.Bool("p", false, "write result to standard output instead of ours")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 3 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	baseFile, oursFile, theirsFile := flag.Arg(0), flag.Arg(1), flag.Arg(2)
+	mode := fileMode(oursFile)
+	ours, theirs := readFile(oursFile), readFile(theirsFile)
+	merged, conflicts := pot.Merge(cli.ParseFile(baseFile), ours.nodes, theirs.nodes)
+	out, err := format(ours, theirs, merged, conflicts)
+	if err != nil {
+		cli.Fatalf("Failed to format POT, %s\n", err)
+	}
+
+	switch {
+	case *stdout:
+		_, err = os.Stdout.Write(out)
+	case !bytes.Equal(out, ours.text):
+		err = os.WriteFile(oursFile, out, mode)
+	}
+	if err != nil {
+		cli.Fatalf("Failed to write result, %s\n", err)
+	}
+	if conflicts > 0 {
+		fmt.Fprintf(os.Stderr, "%s: %d merge conflicts\n", oursFile, conflicts)
+		os.Exit(1)
+	}
+}
+
+// Text and document nodes of a file.
+type file struct {
+	text  []byte
+	nodes []*pot.Node
+}
+
+// Read and parse file. Exits on errors.
+func readFile(name string) *file {
+	text, name, err := cli.ReadFile(name)
+	if err != nil {
+		cli.Fatalf("Failed to read file, %s\n", err)
+	}
+	return &file{text, cli.Parse(name, text)}
+}
+
+// Format the result of merging ours and theirs. The text of ours or theirs is
+// returned as is if the result equals its document, which keeps its comments
+// and layout.
+func format(ours, theirs *file, merged []*pot.Node, conflicts int) ([]byte, error) {
+	if conflicts == 0 {
+		for _, f := range []*file{ours, theirs} {
+			if len(pot.Diff(f.nodes, merged)) == 0 {
+				return f.text, nil
+			}
+		}
+	}
+	return printer.PrintNodes(merged)
+}
+
+// Get the permissions of file.
+func fileMode(file string) os.FileMode {
+	info, err := os.Stat(file)
+	if err != nil {
+		cli.Fatalf("%s\n", err)
+	}
+	return info.Mode().Perm()
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/johan-bolmsjo/pot"
+)
+
+// Test that the text of ours or theirs is kept if the merge result equals it.
+func TestFormat(t *testing.T) {
+	const base = "{ a: 1 b: 2 }\n"
+	tests := []struct {
+		ours, theirs string
+		out          string
+	}{
+		{"{ a: 3 b: 2 } # Ours.\n", "# Theirs.\n{ a: 1 b: 2 }\n", "{ a: 3 b: 2 } # Ours.\n"},
+		{"# Ours.\n{ a: 1 b: 2 }\n", "{ a: 1 b: 3 } # Theirs.\n", "{ a: 1 b: 3 } # Theirs.\n"},
+		{"# Ours.\n{ a: 3 b: 2 }\n", "{ a: 1 b: 3 } # Theirs.\n", "{\n    a: 3\n    b: 3\n}\n"},
+		{"{ a: 3 b: 2 }\n", "{ a: 4 b: 2 }\n",
+			"{\n<<<<<<< ours\n    a: 3\n||||||| base\n    a: 1\n=======\n    a: 4\n>>>>>>> theirs\n    b: 2\n}\n"},
+	}
+	parse := func(text string) *file {
+		nodes, err := pot.Parse([]byte(text))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &file{[]byte(text), nodes}
+	}
+	for _, test := range tests {
+		ours, theirs := parse(test.ours), parse(test.theirs)
+		merged, conflicts := pot.Merge(parse(base).nodes, ours.nodes, theirs.nodes)
+		out, err := format(ours, theirs, merged, conflicts)
+		if err != nil || string(out) != test.out {
+			t.Errorf("%q, %q: format() = (%q, %v) want %q", test.ours, test.theirs, out, err, test.out)
+		}
+	}
+}
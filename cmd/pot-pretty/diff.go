@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Number of unchanged lines shown around changes in unified diffs.
+const diffContext = 3
+
+// Kind of line edit.
+type editKind int
+
+const (
+	editEqual editKind = iota
+	editDelete
+	editInsert
+)
+
+// Line edit turning one text into another.
+type edit struct {
+	kind editKind
+	line []byte // Line including any new-line.
+}
+
+// Create a unified diff turning text a into text b.
+// Returns nil if the texts are equal.
+func unifiedDiff(nameA, nameB string, a, b []byte) []byte {
+	edits := diffLines(splitLines(a), splitLines(b))
+	var buf bytes.Buffer
+	lineA, lineB := 1, 1 // Line numbers of the next lines.
+	for i := 0; i < len(edits); {
+		if edits[i].kind == editEqual {
+			i++
+			lineA++
+			lineB++
+			continue
+		}
+
+		// Extend the hunk until changes are more than twice the context apart.
+		start := max(i-diffContext, 0)
+		end := i
+		for j := i; j < len(edits) && j-end <= 2*diffContext; j++ {
+			if edits[j].kind != editEqual {
+				end = j + 1
+			}
+		}
+		end = min(end+diffContext, len(edits))
+
+		if buf.Len() == 0 {
+			fmt.Fprintf(&buf, "--- %s\n+++ %s\n", nameA, nameB)
+		}
+		startA, startB := lineA-(i-start), lineB-(i-start)
+		countA, countB := 0, 0
+		for _, e := range edits[start:end] {
+			if e.kind != editInsert {
+				countA++
+			}
+			if e.kind != editDelete {
+				countB++
+			}
+		}
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n", hunkRange(startA, countA), hunkRange(startB, countB))
+		for _, e := range edits[start:end] {
+			buf.WriteByte(" -+"[e.kind])
+			buf.Write(e.line)
+			if len(e.line) == 0 || e.line[len(e.line)-1] != '\n' {
+				buf.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		lineA, lineB = startA+countA, startB+countB
+		i = end
+	}
+	return buf.Bytes()
+}
+
+// Format a hunk line range.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start-- // Empty ranges refer to the line before.
+	}
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// Split text into lines keeping new-lines.
+func splitLines(text []byte) [][]byte {
+	var lines [][]byte
+	for len(text) > 0 {
+		n := bytes.IndexByte(text, '\n') + 1
+		if n == 0 {
+			n = len(text)
+		}
+		lines = append(lines, text[:n])
+		text = text[n:]
+	}
+	return lines
+}
+
+// Find the shortest sequence of line edits turning a into b using the linear
+// space variant of the Myers difference algorithm.
+func diffLines(a, b [][]byte) []edit {
+	return appendEdits(nil, a, b)
+}
+
+// Append the edits turning a into b to edits. The common prefix and suffix are
+// stripped and the remaining lines are split at the middle snake of the
+// shortest edit path, which keeps memory use linear in the number of lines.
+func appendEdits(edits []edit, a, b [][]byte) []edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && bytes.Equal(a[prefix], b[prefix]) {
+		edits = append(edits, edit{editEqual, a[prefix]})
+		prefix++
+	}
+	a, b = a[prefix:], b[prefix:]
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && bytes.Equal(a[len(a)-1-suffix], b[len(b)-1-suffix]) {
+		suffix++
+	}
+	tail := a[len(a)-suffix:]
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	switch {
+	case len(a) == 0:
+		for _, line := range b {
+			edits = append(edits, edit{editInsert, line})
+		}
+	case len(b) == 0:
+		for _, line := range a {
+			edits = append(edits, edit{editDelete, line})
+		}
+	default:
+		// Both a and b are non-empty without a common prefix or suffix, so
+		// the edit path has at least two edits and both halves are smaller.
+		x, y, u, v := middleSnake(a, b)
+		edits = appendEdits(edits, a[:x], b[:y])
+		for _, line := range a[x:u] {
+			edits = append(edits, edit{editEqual, line})
+		}
+		edits = appendEdits(edits, a[u:], b[v:])
+	}
+	for _, line := range tail {
+		edits = append(edits, edit{editEqual, line})
+	}
+	return edits
+}
+
+// Find the middle snake of a shortest edit path turning a into b by searching
+// forward from the start and backward from the end at the same time. Returns
+// the start (x, y) and end (u, v) of the snake.
+func middleSnake(a, b [][]byte) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	max := (n + m + 1) / 2
+	offset := max + 1
+	vf := make([]int, 2*offset+1) // Furthest x on forward diagonal k, indexed by k+offset.
+	vb := make([]int, 2*offset+1) // Furthest x on backward diagonal k counting from the end.
+
+	for d := 0; d <= max; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && vf[k-1+offset] < vf[k+1+offset] {
+				x = vf[k+1+offset] // Insert, move down.
+			} else {
+				x = vf[k-1+offset] + 1 // Delete, move right.
+			}
+			y := x - k
+			x0, y0 := x, y
+			for x < n && y < m && bytes.Equal(a[x], b[y]) {
+				x++
+				y++
+			}
+			vf[k+offset] = x
+			if kb := delta - k; delta%2 != 0 && kb >= -(d-1) && kb <= d-1 && x+vb[kb+offset] >= n {
+				return x0, y0, x, y
+			}
+		}
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && vb[k-1+offset] < vb[k+1+offset] {
+				x = vb[k+1+offset]
+			} else {
+				x = vb[k-1+offset] + 1
+			}
+			y := x - k
+			x0, y0 := x, y
+			for x < n && y < m && bytes.Equal(a[n-1-x], b[m-1-y]) {
+				x++
+				y++
+			}
+			vb[k+offset] = x
+			if kf := delta - k; delta%2 == 0 && kf >= -d && kf <= d && x+vf[kf+offset] >= n {
+				return n - x, m - y, n - x0, m - y0
+			}
+		}
+	}
+	panic("no middle snake")
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		a, b string
+		diff string
+	}{
+		{"a\nb\n", "a\nb\n", ""},
+		{"a\nb\nc\n", "a\nx\nc\n", "--- a\n+++ b\n@@ -1,3 +1,3 @@\n a\n-b\n+x\n c\n"},
+		{"", "a\n", "--- a\n+++ b\n@@ -0,0 +1 @@\n+a\n"},
+		{"a", "a\n", "--- a\n+++ b\n@@ -1 +1 @@\n-a\n\\ No newline at end of file\n+a\n"},
+		{"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n", "0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			"--- a\n+++ b\n@@ -1,3 +1,4 @@\n+0\n 1\n 2\n 3\n@@ -7,4 +8,3 @@\n 7\n 8\n 9\n-10\n"},
+		{"1\n2\n3\n4\n5\n6\n7\n", "0\n1\n2\n3\n4\n5\n6\n",
+			"--- a\n+++ b\n@@ -1,7 +1,7 @@\n+0\n 1\n 2\n 3\n 4\n 5\n 6\n-7\n"},
+	}
+	for _, test := range tests {
+		if diff := string(unifiedDiff("a", "b", []byte(test.a), []byte(test.b))); diff != test.diff {
+			t.Errorf("diff(%q, %q) =\n%s\nwant\n%s", test.a, test.b, diff, test.diff)
+		}
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	randomLines := func() [][]byte {
+		var s []string
+		for i := rnd.Intn(20); i > 0; i-- {
+			s = append(s, string(rune('a'+rnd.Intn(4)))+"\n")
+		}
+		return splitLines([]byte(strings.Join(s, "")))
+	}
+	for i := 0; i < 1000; i++ {
+		a, b := randomLines(), randomLines()
+		var gotA, gotB [][]byte
+		changes := 0
+		for _, e := range diffLines(a, b) {
+			if e.kind != editInsert {
+				gotA = append(gotA, e.line)
+			}
+			if e.kind != editDelete {
+				gotB = append(gotB, e.line)
+			}
+			if e.kind != editEqual {
+				changes++
+			}
+		}
+		if !bytes.Equal(bytes.Join(a, nil), bytes.Join(gotA, nil)) || !bytes.Equal(bytes.Join(b, nil), bytes.Join(gotB, nil)) {
+			t.Fatalf("edits of %q and %q do not reproduce the texts", a, b)
+		}
+		if want := len(a) + len(b) - 2*lcsLength(a, b); changes != want {
+			t.Fatalf("edits of %q and %q have %d changes want %d", a, b, changes, want)
+		}
+	}
+}
+
+// Get the length of the longest common subsequence of lines in a and b.
+func lcsLength(a, b [][]byte) int {
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			switch {
+			case bytes.Equal(a[i], b[j]):
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] > cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// Benchmark diffing a text against a re-indented copy of it, where every line
+// differs.
+func BenchmarkDiffLines_Reindented(b *testing.B) {
+	var s, t strings.Builder
+	for i := 0; i < 4000; i++ {
+		fmt.Fprintf(&s, "  key%d: value\n", i)
+		fmt.Fprintf(&t, "    key%d: value\n", i)
+	}
+	a, c := splitLines([]byte(s.String())), splitLines([]byte(t.String()))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		diffLines(a, c)
+	}
+}
@@ -1,27 +1,165 @@
 package main
 
 import (
+	"bytes"
+	"flag"
 	"fmt"
-	"io/ioutil"
+	"io/fs"
 	"os"
+	"path/filepath"
 
 	"github.com/johan-bolmsjo/pot"
+	"github.com/johan-bolmsjo/pot/internal/cli"
+)
+
+const usage = `Usage: pot-pretty [flags] [path ...]
+
+Pretty print POT files. Directories are processed recursively for files with
+the .pot extension. Standard input is pretty printed to standard output if no
+paths are given.
+
+Files are checked and rewritten with -l, -d and -w keeping their comments, blank
+lines and line breaks in lists unless -k=false is given. Standard output keeps
+them with -k only.
+
+With -l or -d the exit status is 1 if any file is not pretty printed. The exit
+status is 2 if an error occurred.
+
+Flags:
+`
+
+var (
+	list  = flag.Bool("l", false, "list files whose formatting differs from pretty printed output")
+	write = flag.Bool("w", false, "write result to source files instead of standard output")
+	diff  = flag.Bool("d", false, "display diffs instead of rewriting files")
+	keep  = flag.Bool("k", false, "keep comments, blank lines and line breaks in lists (default with -l, -w and -d)")
+)
+
+// Printer used to pretty print files.
+var printer = pot.Printer{AlignKeys: true, FinalNewline: true}
+
+// Exit status.
+var (
+	changed bool // Any file differs from its pretty printed output.
+	failed  bool // Any file could not be processed.
 )
 
 func main() {
-	buf, err := ioutil.ReadAll(os.Stdin)
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	setKeepLayout(flag.CommandLine)
+
+	if flag.NArg() == 0 {
+		if *write {
+			cli.Fatalf("Can not use -w with standard input\n")
+		}
+		buf, name, err := cli.ReadFile("-")
+		if err != nil {
+			cli.Fatalf("Failed to read file, %s\n", err)
+		}
+		processFile(name, buf, 0)
+	}
+	for _, path := range flag.Args() {
+		info, err := os.Stat(path)
+		switch {
+		case err != nil:
+			report(err)
+		case info.IsDir():
+			walkDir(path)
+		default:
+			processPath(path, info.Mode())
+		}
+	}
+
+	switch {
+	case failed:
+		os.Exit(2)
+	case changed && (*list || *diff):
+		os.Exit(1)
+	}
+}
+
+// Set the layout of the printer from the flags. Files checked or rewritten in
+// place keep their layout unless -k=false is given, as pretty printing them
+// would otherwise delete their comments.
+func setKeepLayout(flags *flag.FlagSet) {
+	printer.KeepLayout = *keep
+	if *list || *write || *diff {
+		printer.KeepLayout = true
+		flags.Visit(func(f *flag.Flag) {
+			if f.Name == "k" {
+				printer.KeepLayout = *keep
+			}
+		})
+	}
+}
+
+// Process all POT files in directory tree.
+func walkDir(dir string) {
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && filepath.Ext(path) == ".pot" {
+			var info fs.FileInfo
+			if info, err = d.Info(); err == nil {
+				processPath(path, info.Mode())
+			}
+		}
+		if err != nil {
+			report(err)
+		}
+		return nil
+	})
+	if err != nil {
+		report(err)
+	}
+}
+
+// Read and process file at path.
+func processPath(path string, mode fs.FileMode) {
+	buf, err := os.ReadFile(path)
 	if err != nil {
-		fatalf("Faild to read from stdin, %s\n")
+		report(err)
+		return
 	}
+	processFile(path, buf, mode)
+}
 
-	if buf, err = pot.PrettyPrint(buf); err != nil {
-		fatalf("Failed to pretty print POT, %s\n", err)
+// Pretty print the text of a file and list, write or diff the result as
+// requested by the flags. Mode is the file mode used when writing the file.
+func processFile(name string, buf []byte, mode fs.FileMode) {
+	out, err := printer.Print(buf)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to pretty print POT, %s", cli.Render(name, buf, err))
+		failed = true
+		return
 	}
 
-	fmt.Println(string(buf))
+	if !*list && !*write && !*diff {
+		os.Stdout.Write(out)
+		return
+	}
+	if bytes.Equal(buf, out) {
+		return
+	}
+	changed = true
+	if *list {
+		fmt.Println(name)
+	}
+	if *write {
+		if err := os.WriteFile(name, out, mode.Perm()); err != nil {
+			report(err)
+			return
+		}
+	}
+	if *diff {
+		os.Stdout.Write(unifiedDiff(name+".orig", name, buf, out))
+	}
 }
 
-func fatalf(format string, a ...interface{}) {
-	fmt.Fprintf(os.Stderr, format, a...)
-	os.Exit(1)
+// Report an error without stopping.
+func report(err error) {
+	fmt.Fprintf(os.Stderr, "%s\n", err)
+	failed = true
 }
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Test that files rewritten or checked in place keep their comments unless
+// -k=false is given.
+func TestKeepLayout(t *testing.T) {
+	tests := []struct {
+		args    []string
+		in, out string
+		changed bool
+	}{
+		{[]string{"-w"}, "# Comment.\n{ a: b }\n", "# Comment.\n{\n    a: b\n}\n", true},
+		{[]string{"-w", "-k=false"}, "# Comment.\n{ a: b }\n", "{\n    a: b\n}\n", true},
+		{[]string{"-l"}, "# Comment.\n{\n    a: b\n}\n", "# Comment.\n{\n    a: b\n}\n", false},
+	}
+	for _, test := range tests {
+		// Parse the arguments into the command line flag values.
+		flags := flag.NewFlagSet("pot-pretty", flag.ContinueOnError)
+		for _, name := range []string{"l", "w", "d", "k"} {
+			f := flag.Lookup(name)
+			f.Value.Set(f.DefValue)
+			flags.Var(f.Value, f.Name, f.Usage)
+		}
+		if err := flags.Parse(test.args); err != nil {
+			t.Fatal(err)
+		}
+		setKeepLayout(flags)
+		changed, failed = false, false
+
+		path := filepath.Join(t.TempDir(), "a.pot")
+		if err := os.WriteFile(path, []byte(test.in), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		processPath(path, 0o644)
+		buf, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf) != test.out || changed != test.changed || failed {
+			t.Errorf("%q: file = %q, changed = %t, failed = %t want %q, %t", test.args, buf, changed, failed, test.out, test.changed)
+		}
+	}
+}
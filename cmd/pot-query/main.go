@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/johan-bolmsjo/pot"
+	"github.com/johan-bolmsjo/pot/internal/cli"
+)
+
+const usage = `Usage: pot-query [flags] path [file ...]
+
+Select values from POT files or stdin with a path expression such as
+"servers[2].ports" and print them. See the pot.Path documentation for the
+path syntax. A file named "-" is read from stdin.
+
+The exit status is 0 if any value was selected, 1 if none was and 2 if an
+error occurred.
+
+Flags:
+`
+
+func main() {
+	raw := flag.Bool("r", false, "print strings raw with escape codes evaluated")
+	location := flag.Bool("l", false, "prefix values with file:line:column")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	path, err := pot.CompilePath(flag.Arg(0))
+	if err != nil {
+		cli.Fatalf("%s\n", err)
+	}
+
+	q := query{path: path, raw: *raw, location: *location, out: bufio.NewWriter(os.Stdout)}
+	files := flag.Args()[1:]
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	for _, file := range files {
+		buf, name, err := cli.ReadFile(file)
+		if err != nil {
+			cli.Fatalf("Failed to read file, %s\n", err)
+		}
+		q.run(name, buf)
+	}
+
+	if err := q.out.Flush(); err != nil {
+		cli.Fatalf("Failed to write to stdout, %s\n", err)
+	}
+	if !q.found {
+		os.Exit(1)
+	}
+}
+
+// Query applied to POT text inputs.
+type query struct {
+	path     *pot.Path
+	raw      bool // Print strings raw.
+	location bool // Prefix values with their location.
+	out      *bufio.Writer
+	found    bool // Any value has been selected.
+}
+
+// Run the query on the text input of file and print the selected values.
+func (q *query) run(file string, buf []byte) {
+	nodes, err := pot.Parse(buf)
+	if err != nil {
+		q.out.Flush()
+		cli.Fatalf("Failed to parse POT, %s", cli.Render(file, buf, err))
+	}
+
+	for _, node := range q.path.Select(nodes...) {
+		q.found = true
+		if q.location {
+			fmt.Fprintf(q.out, "%s:%s: ", file, node.Location)
+		}
+		if q.raw && node.Kind == pot.StringNode {
+			fmt.Fprintln(q.out, node.Value)
+			continue
+		}
+		out, err := pot.FormatNodes([]*pot.Node{node})
+		if err != nil {
+			q.out.Flush()
+			cli.Fatalf("Failed to format POT, %s\n", err)
+		}
+		q.out.Write(out)
+		q.out.WriteByte('\n')
+	}
+}
@@ -0,0 +1,368 @@
+package pot
+
+import (
+	"encoding"
+	"io"
+	"reflect"
+	"strconv"
+)
+
+// Error returned when an invalid argument is passed to Unmarshal.
+// The argument must be a non-nil pointer.
+type InvalidUnmarshalError struct {
+	Type reflect.Type
+}
+
+// Implements error.
+func (err *InvalidUnmarshalError) Error() string {
+	if err.Type == nil {
+		return "unmarshal of nil"
+	}
+	if err.Type.Kind() != reflect.Ptr {
+		return "unmarshal of non-pointer " + err.Type.String()
+	}
+	return "unmarshal of nil " + err.Type.String()
+}
+
+// Unmarshaler is implemented by types that can unmarshal themselves from a
+// POT parser. The parser is a Dict, List or String positioned at the value
+// to unmarshal, with locations relative to the original text input.
+type Unmarshaler interface {
+	UnmarshalPOT(parser Parser) error
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// Unmarshal POT text into the value pointed to by v.
+//
+// The text must contain exactly one root level value. Values implementing
+// Unmarshaler are handed the parser of the value to unmarshal. Dictionaries are
+// unmarshaled into structs, maps and empty interfaces, lists into slices, arrays
+// and empty interfaces and strings into string, boolean and numeric types,
+// byte slices, empty interfaces and types implementing
+// encoding.TextUnmarshaler. Pointers are allocated as needed.
+//
+// Dictionary keys are matched against struct field names or the name given by
+// a `pot:"name"` field tag, preferring an exact match over a case insensitive
+// one. Fields tagged with `pot:"-"` are ignored as are keys without a matching
+// field. The last value wins if a key occurs more than once.
+//
+// Errors are returned as *ParseError values with the location of the offending
+// node.
+func Unmarshal(pot []byte, v interface{}) error {
+	parser, err := scanSingleValue(pot)
+	if err != nil {
+		return err
+	}
+	return UnmarshalParser(parser, v)
+}
+
+// Scans a text buffer that must contain exactly one root level value.
+// Returns a Dict, List or String parser or an error.
+func scanSingleValue(pot []byte) (Parser, error) {
+	root := newRoot(newParserBuf(pot))
+	parser, err := root.nextValue()
+	if err != nil {
+		return nil, err
+	}
+	if parser == nil {
+		return nil, root.stream.token(root.pos).Location.errorf(ErrMissingValue, "end of input while parsing value")
+	}
+	next, err := root.nextValue()
+	if err != nil {
+		return nil, err
+	}
+	if next != nil {
+		return nil, next.Location().errorf(ErrUnexpectedValue, "unexpected %s after root level value", next.Name())
+	}
+	return parser, nil
+}
+
+// Unmarshal the value produced by parser into the value pointed to by v.
+// The parser may be a Dict, List or String. See Unmarshal for details.
+func UnmarshalParser(parser Parser, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &InvalidUnmarshalError{reflect.TypeOf(v)}
+	}
+	return decodeValue(parser, rv.Elem())
+}
+
+// Decoder reading POT values from an input stream.
+type Decoder struct {
+	parser *ReaderParser
+}
+
+// Create a new decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{NewReaderParser(r)}
+}
+
+// Set the options used to parse the stream.
+// Must be called before the first call to Decode.
+func (dec *Decoder) SetParserOptions(options *ParserOptions) {
+	dec.parser.options = options
+}
+
+// Read the next root level value from the stream and unmarshal it into the
+// value pointed to by v. Returns io.EOF when there are no more values.
+// See Unmarshal for details.
+func (dec *Decoder) Decode(v interface{}) error {
+	parser, err := dec.parser.Next()
+	if err != nil {
+		return err
+	}
+	if parser == nil {
+		return io.EOF
+	}
+	return UnmarshalParser(parser, v)
+}
+
+// Decode a Dict, List or String parser into v.
+func decodeValue(parser Parser, v reflect.Value) error {
+	if u, _, _ := indirect(v, false); u != nil {
+		if err := u.UnmarshalPOT(parser); err != nil {
+			if _, ok := err.(*ParseError); ok {
+				return err
+			}
+			return parser.Location().errorf(ErrUnmarshal, "%s", err)
+		}
+		return nil
+	}
+
+	switch parser := parser.(type) {
+	case *Dict:
+		return decodeDict(parser, v)
+	case *List:
+		return decodeList(parser, v)
+	case *String:
+		return decodeString(parser, v)
+	}
+	return parser.Location().errorf(ErrUnmarshal, "cannot unmarshal %s", parser.Name())
+}
+
+// Decode a dictionary into a struct, map or empty interface.
+func decodeDict(dict *Dict, v reflect.Value) error {
+	_, _, v = indirect(v, false)
+
+	var fields []field
+	switch v.Kind() {
+	case reflect.Struct:
+		fields = cachedFields(v.Type())
+	case reflect.Map:
+		kt := v.Type().Key()
+		if kt.Kind() != reflect.String && !reflect.PtrTo(kt).Implements(textUnmarshalerType) {
+			return unmarshalTypeError(dict, v.Type())
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return unmarshalTypeError(dict, v.Type())
+		}
+		m := make(map[string]interface{})
+		if err := decodeDict(dict, reflect.ValueOf(m)); err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(m))
+		return nil
+	default:
+		return unmarshalTypeError(dict, v.Type())
+	}
+
+	var key *DictKey
+	scanner := NewParserScanner(dict)
+	for scanner.Scan() {
+		switch subparser := scanner.SubParser().(type) {
+		case *DictKey:
+			key = subparser
+		default:
+			var err error
+			if v.Kind() == reflect.Struct {
+				if f := findField(fields, string(key.Bytes())); f != nil {
+					err = decodeValue(subparser, v.FieldByIndex(f.index))
+				}
+			} else {
+				err = decodeMapEntry(key, subparser, v)
+			}
+			if err != nil {
+				scanner.InjectError(err)
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// Decode a dictionary entry into map m.
+func decodeMapEntry(key *DictKey, parser Parser, m reflect.Value) error {
+	elem := reflect.New(m.Type().Elem()).Elem()
+	if err := decodeValue(parser, elem); err != nil {
+		return err
+	}
+
+	kt := m.Type().Key()
+	var kv reflect.Value
+	if reflect.PtrTo(kt).Implements(textUnmarshalerType) {
+		kv = reflect.New(kt)
+		if err := kv.Interface().(encoding.TextUnmarshaler).UnmarshalText(key.Bytes()); err != nil {
+			return key.Location().errorf(ErrUnmarshal, "%s", err)
+		}
+		kv = kv.Elem()
+	} else {
+		kv = reflect.ValueOf(string(key.Bytes())).Convert(kt)
+	}
+	m.SetMapIndex(kv, elem)
+	return nil
+}
+
+// Decode a list into a slice, array or empty interface.
+func decodeList(list *List, v reflect.Value) error {
+	_, _, v = indirect(v, false)
+
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.IsNil() {
+			v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+		} else {
+			v.SetLen(0)
+		}
+	case reflect.Array:
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return unmarshalTypeError(list, v.Type())
+		}
+		var s []interface{}
+		if err := decodeList(list, reflect.ValueOf(&s).Elem()); err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(s))
+		return nil
+	default:
+		return unmarshalTypeError(list, v.Type())
+	}
+
+	i := 0
+	scanner := NewParserScanner(list)
+	for scanner.Scan() {
+		subparser := scanner.SubParser()
+		if v.Kind() == reflect.Array {
+			if i >= v.Len() {
+				scanner.InjectError(subparser.Location().errorf(ErrUnmarshal, "too many values for Go array of type %s", v.Type()))
+				continue
+			}
+		} else {
+			v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+		}
+		if err := decodeValue(subparser, v.Index(i)); err != nil {
+			scanner.InjectError(err)
+		}
+		i++
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	for ; v.Kind() == reflect.Array && i < v.Len(); i++ {
+		v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+	}
+	return nil
+}
+
+// Decode a string into a TextUnmarshaler, string, boolean or numeric type,
+// byte slice or empty interface.
+func decodeString(str *String, v reflect.Value) error {
+	_, u, v := indirect(v, true)
+	if u != nil {
+		if err := u.UnmarshalText(str.Bytes()); err != nil {
+			return str.Location().errorf(ErrUnmarshal, "%s", err)
+		}
+		return nil
+	}
+
+	s := string(str.Bytes())
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return invalidValueError(str, v.Type())
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return invalidValueError(str, v.Type())
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return invalidValueError(str, v.Type())
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return invalidValueError(str, v.Type())
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return unmarshalTypeError(str, v.Type())
+		}
+		v.SetBytes([]byte(s))
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			return unmarshalTypeError(str, v.Type())
+		}
+		v.Set(reflect.ValueOf(s))
+	default:
+		return unmarshalTypeError(str, v.Type())
+	}
+	return nil
+}
+
+// Follow pointers, allocating new values as needed, until a non pointer value
+// is reached. If a value implementing Unmarshaler is found along the way it is
+// returned instead. The same goes for encoding.TextUnmarshaler if wantText is
+// true.
+func indirect(v reflect.Value, wantText bool) (Unmarshaler, encoding.TextUnmarshaler, reflect.Value) {
+	if v.Kind() != reflect.Ptr && v.CanAddr() {
+		v = v.Addr()
+	}
+	for {
+		if v.Kind() == reflect.Interface && !v.IsNil() {
+			if e := v.Elem(); e.Kind() == reflect.Ptr && !e.IsNil() {
+				v = e
+				continue
+			}
+		}
+		if v.Kind() != reflect.Ptr {
+			break
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		if v.Type().NumMethod() > 0 && v.CanInterface() {
+			if u, ok := v.Interface().(Unmarshaler); ok {
+				return u, nil, reflect.Value{}
+			}
+			if u, ok := v.Interface().(encoding.TextUnmarshaler); ok && wantText {
+				return nil, u, reflect.Value{}
+			}
+		}
+		v = v.Elem()
+	}
+	return nil, nil, v
+}
+
+// Create an error for a parser that can't be unmarshaled into Go type t.
+func unmarshalTypeError(parser Parser, t reflect.Type) error {
+	return parser.Location().errorf(ErrUnmarshal, "cannot unmarshal %s into Go value of type %s", parser.Name(), t)
+}
+
+// Create an error for a string that is not a valid value of Go type t.
+func invalidValueError(str *String, t reflect.Type) error {
+	return str.Location().errorf(ErrUnmarshal, "invalid value %s for Go value of type %s", str, t)
+}
@@ -0,0 +1,156 @@
+package pot
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+)
+
+type testAnimal struct {
+	Animal      string
+	Class       string
+	WeightRange []string `pot:"weight-range"`
+	Foods       map[string]float64
+	Legs        *int
+	Address     net.IP
+	Ignored     string `pot:"-"`
+}
+
+func ExampleUnmarshal() {
+	var animal testAnimal
+	err := Unmarshal([]byte(`
+{ animal:       zebra
+  class:        mammal
+  weight-range: [ 240kg 370kg ]
+  foods:        { grass: 0.9 apples: 0.1 }
+  legs:         4
+  address:      127.0.0.1 }`), &animal)
+	if err != nil {
+		fmt.Printf("error: %s\n", err)
+		return
+	}
+	fmt.Println(animal.Animal, animal.Class, animal.WeightRange, animal.Foods["apples"], *animal.Legs, animal.Address)
+	// Output:
+	// zebra mammal [240kg 370kg] 0.1 4 127.0.0.1
+}
+
+type testEmbedded struct {
+	A string
+	B string
+}
+
+type testOuter struct {
+	testEmbedded
+	B     string
+	Array [2]int
+	Any   interface{}
+	Bytes []byte
+	Bool  bool
+	Uint  uint8
+	Keys  map[testKey]string
+}
+
+type testKey string
+
+func (key *testKey) UnmarshalText(text []byte) error {
+	*key = testKey("key-" + string(text))
+	return nil
+}
+
+func TestUnmarshal(t *testing.T) {
+	var v testOuter
+	pot := `{ A: a B: b Array: [ 1 ] Any: { x: [ y z ] } Bytes: bytes Bool: true Uint: 255 Keys: { k: v } }`
+	if err := Unmarshal([]byte(pot), &v); err != nil {
+		t.Fatal(err)
+	}
+	want := testOuter{
+		testEmbedded: testEmbedded{A: "a"},
+		B:            "b",
+		Array:        [2]int{1, 0},
+		Any:          map[string]interface{}{"x": []interface{}{"y", "z"}},
+		Bytes:        []byte("bytes"),
+		Bool:         true,
+		Uint:         255,
+		Keys:         map[testKey]string{"key-k": "v"},
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("Unmarshal() = %+v want %+v", v, want)
+	}
+}
+
+func TestUnmarshal_Errors(t *testing.T) {
+	tests := []struct {
+		pot string
+		v   interface{}
+		err string
+	}{
+		{"", new(string), "1:0: end of input while parsing value"},
+		{"a b", new(string), "1:2: unexpected string after root level value"},
+		{"{ a: b }", new(string), "1:0: cannot unmarshal dictionary into Go value of type string"},
+		{"[ a ]", new(map[string]string), "1:0: cannot unmarshal list into Go value of type map[string]string"},
+		{"{ Uint: 256 }", new(testOuter), "1:8: invalid value 256 for Go value of type uint8"},
+		{"{ Array: [ 1 2 3 ] }", new(testOuter), "1:15: too many values for Go array of type [2]int"},
+		{"{ Address: 1.2.3 }", new(testAnimal), "1:11: invalid IP address: 1.2.3"},
+		{"{ a: b }", new(map[int]string), "1:0: cannot unmarshal dictionary into Go value of type map[int]string"},
+	}
+	for _, test := range tests {
+		err := Unmarshal([]byte(test.pot), test.v)
+		if _, ok := err.(*ParseError); !ok || err.Error() != test.err {
+			t.Errorf("Unmarshal(%q) = %v want %s", test.pot, err, test.err)
+		}
+	}
+
+	var s string
+	for _, v := range []interface{}{nil, s, (*string)(nil)} {
+		if err := Unmarshal([]byte("a"), v); err == nil {
+			t.Errorf("Unmarshal(%v) = nil want error", v)
+		}
+	}
+}
+
+// Point type consuming a list of two coordinates.
+type testPoint struct {
+	X, Y     int
+	Location Location
+}
+
+func (p *testPoint) UnmarshalPOT(parser Parser) error {
+	p.Location = parser.Location()
+	var xy []int
+	if err := UnmarshalParser(parser, &xy); err != nil {
+		return err
+	}
+	if len(xy) != 2 {
+		return fmt.Errorf("expected 2 coordinates, got %d", len(xy))
+	}
+	p.X, p.Y = xy[0], xy[1]
+	return nil
+}
+
+func (p testPoint) MarshalPOT() ([]byte, error) {
+	return []byte(fmt.Sprintf("[\n\t%d\n\t%d\n]", p.X, p.Y)), nil
+}
+
+func TestUnmarshal_Unmarshaler(t *testing.T) {
+	var v struct {
+		Points []testPoint
+	}
+	if err := Unmarshal([]byte("{\n  Points: [ [ 1 2 ]\n    [ 3 4 ] ] }"), &v); err != nil {
+		t.Fatal(err)
+	}
+	want := []testPoint{{1, 2, Location{1, 12}}, {3, 4, Location{2, 4}}}
+	if !reflect.DeepEqual(v.Points, want) {
+		t.Errorf("Unmarshal() = %+v want %+v", v.Points, want)
+	}
+
+	errs := map[string]string{
+		"{ Points: [ [ 1 ] ] }":   "1:12: expected 2 coordinates, got 1",
+		"{ Points: [ [ 1 x ] ] }": "1:16: invalid value x for Go value of type int",
+	}
+	for pot, want := range errs {
+		if err := Unmarshal([]byte(pot), &v); err == nil || err.Error() != want {
+			t.Errorf("Unmarshal(%q) = %v want %s", pot, err, want)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package pot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Kind of difference between two documents.
+type ChangeKind int
+
+const (
+	Added   ChangeKind = iota // Value only in the new document.
+	Removed                   // Value only in the old document.
+	Changed                   // String value or kind of value differs.
+)
+
+// Implements fmt.Stringer.
+func (kind ChangeKind) String() string {
+	switch kind {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Changed:
+		return "changed"
+	}
+	return "unknown"
+}
+
+// Difference between two documents found by Diff.
+type Change struct {
+	Kind ChangeKind
+
+	// Path expression selecting the value from the root level values of the
+	// new document, or of the old document for removed values. See Path. The
+	// paths of a value in the two documents differ when list items before it
+	// were added or removed.
+	Path string
+
+	Old *Node // Value in the old document, nil for added values.
+	New *Node // Value in the new document, nil for removed values.
+
+	// Locations of the values in the text inputs. The location of an added or
+	// removed value on the side it's missing from is the location of the
+	// dictionary or list it's missing from.
+	OldLocation Location
+	NewLocation Location
+}
+
+// Implements fmt.Stringer.
+func (change *Change) String() string {
+	switch change.Kind {
+	case Added:
+		return fmt.Sprintf("%s %s: %s", change.Kind, change.Path, change.New)
+	case Removed:
+		return fmt.Sprintf("%s %s: %s", change.Kind, change.Path, change.Old)
+	}
+	return fmt.Sprintf("%s %s: %s -> %s", change.Kind, change.Path, change.Old, change.New)
+}
+
+// Maximum number of list item pairs compared to find the longest common
+// subsequence of lists. Larger lists are compared by position.
+const maxLCSCells = 1 << 20
+
+// Compare two documents structurally and return their differences.
+//
+// Dictionary entries are matched by key and by their index among the entries
+// with the same key, so that reordering entries with different keys is not a
+// change. List items are matched using the longest common subsequence of equal
+// items, remaining items between matches are compared by position. Values of
+// different kinds or strings with different values are changed. Changes are
+// ordered by the entries of the old dictionaries followed by added entries and
+// by position in lists.
+//
+// Documents with a single root level value each are compared directly. Other
+// documents are compared as lists of root level values, making paths start
+// with a '#n' step selecting the root level value.
+func Diff(oldNodes, newNodes []*Node) []Change {
+	var d differ
+	if len(oldNodes) == 1 && len(newNodes) == 1 {
+		d.diff("", "", oldNodes[0], newNodes[0])
+	} else {
+		d.roots = true
+		d.diffList("", "", NewList(oldNodes...), NewList(newNodes...))
+	}
+	return d.changes
+}
+
+// State of a structural comparison.
+type differ struct {
+	changes []Change
+	roots   bool // Compare lists of root level values.
+}
+
+// Record a change of the value at oldPath in the old document and newPath in
+// the new document.
+func (d *differ) change(kind ChangeKind, oldPath, newPath string, oldNode, newNode *Node, oldLocation, newLocation Location) {
+	path := newPath
+	if kind == Removed {
+		path = oldPath
+	}
+	if path == "" {
+		path = "."
+	}
+	d.changes = append(d.changes, Change{kind, path, oldNode, newNode, oldLocation, newLocation})
+}
+
+// Compare two values at oldPath and newPath.
+func (d *differ) diff(oldPath, newPath string, oldNode, newNode *Node) {
+	switch {
+	case oldNode.Kind != newNode.Kind || oldNode.Kind == StringNode && oldNode.Value != newNode.Value:
+		d.change(Changed, oldPath, newPath, oldNode, newNode, oldNode.Location, newNode.Location)
+	case oldNode.Kind == DictNode:
+		d.diffDict(oldPath, newPath, oldNode, newNode)
+	case oldNode.Kind == ListNode:
+		d.diffList(oldPath, newPath, oldNode, newNode)
+	}
+}
+
+// Compare two dictionaries at oldPath and newPath, matching entries by key and
+// occurrence.
+func (d *differ) diffDict(oldPath, newPath string, oldNode, newNode *Node) {
+	// Index of each entry among the entries with the same key.
+	occurrences := func(node *Node) (map[string]int, []int) {
+		counts := make(map[string]int)
+		index := make([]int, len(node.Entries))
+		for i, entry := range node.Entries {
+			index[i] = counts[entry.Key]
+			counts[entry.Key]++
+		}
+		return counts, index
+	}
+	oldCounts, oldIndex := occurrences(oldNode)
+	newCounts, newIndex := occurrences(newNode)
+
+	type occurrence struct {
+		key   string
+		index int
+	}
+	newEntries := make(map[occurrence]*Entry, len(newNode.Entries))
+	for i := range newNode.Entries {
+		entry := &newNode.Entries[i]
+		newEntries[occurrence{entry.Key, newIndex[i]}] = entry
+	}
+	entryPath := func(path, key string, index int) string {
+		if oldCounts[key] > 1 || newCounts[key] > 1 {
+			return fmt.Sprintf("%s#%d", joinPath(path, key), index)
+		}
+		return joinPath(path, key)
+	}
+
+	for i, entry := range oldNode.Entries {
+		if newEntry := newEntries[occurrence{entry.Key, oldIndex[i]}]; newEntry != nil {
+			d.diff(entryPath(oldPath, entry.Key, oldIndex[i]), entryPath(newPath, entry.Key, oldIndex[i]), entry.Value, newEntry.Value)
+		} else {
+			d.change(Removed, entryPath(oldPath, entry.Key, oldIndex[i]), "", entry.Value, nil, entry.Value.Location, newNode.Location)
+		}
+	}
+	for i, entry := range newNode.Entries {
+		if newIndex[i] >= oldCounts[entry.Key] {
+			d.change(Added, "", entryPath(newPath, entry.Key, newIndex[i]), nil, entry.Value, oldNode.Location, entry.Value.Location)
+		}
+	}
+}
+
+// Compare two lists at oldPath and newPath.
+func (d *differ) diffList(oldPath, newPath string, oldNode, newNode *Node) {
+	oldItems, newItems := oldNode.Items, newNode.Items
+	itemPath := func(path string, index int) string {
+		if d.roots && path == "" {
+			return "#" + strconv.Itoa(index)
+		}
+		return indexPath(path, index)
+	}
+	matches := matchItems(oldItems, newItems)
+	matches = append(matches, [2]int{len(oldItems), len(newItems)})
+
+	i, j := 0, 0
+	for _, match := range matches {
+		// Compare unmatched items before the match by position.
+		for ; i < match[0] && j < match[1]; i, j = i+1, j+1 {
+			d.diff(itemPath(oldPath, i), itemPath(newPath, j), oldItems[i], newItems[j])
+		}
+		for ; i < match[0]; i++ {
+			d.change(Removed, itemPath(oldPath, i), "", oldItems[i], nil, oldItems[i].Location, newNode.Location)
+		}
+		for ; j < match[1]; j++ {
+			d.change(Added, "", itemPath(newPath, j), nil, newItems[j], oldNode.Location, newItems[j].Location)
+		}
+		i, j = match[0]+1, match[1]+1
+	}
+}
+
+// Find equal list items forming the longest common subsequence of two lists.
+// Returns the indices of the matched items in increasing order. Lists too large
+// to compare are matched by their common prefix and suffix only.
+func matchItems(a, b []*Node) [][2]int {
+	keys := func(nodes []*Node) []string {
+		s := make([]string, len(nodes))
+		for i, node := range nodes {
+			s[i] = node.String()
+		}
+		return s
+	}
+	ka, kb := keys(a), keys(b)
+
+	var matches [][2]int
+	prefix := 0
+	for prefix < len(ka) && prefix < len(kb) && ka[prefix] == kb[prefix] {
+		matches = append(matches, [2]int{prefix, prefix})
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(ka)-prefix && suffix < len(kb)-prefix && ka[len(ka)-1-suffix] == kb[len(kb)-1-suffix] {
+		suffix++
+	}
+
+	ma, mb := ka[prefix:len(ka)-suffix], kb[prefix:len(kb)-suffix]
+	if len(ma) > 0 && len(mb) > 0 && len(ma)*len(mb) <= maxLCSCells {
+		// lengths[i][j] is the length of the longest common subsequence of
+		// ma[i:] and mb[j:].
+		lengths := make([][]int, len(ma)+1)
+		for i := range lengths {
+			lengths[i] = make([]int, len(mb)+1)
+		}
+		for i := len(ma) - 1; i >= 0; i-- {
+			for j := len(mb) - 1; j >= 0; j-- {
+				if ma[i] == mb[j] {
+					lengths[i][j] = lengths[i+1][j+1] + 1
+				} else {
+					lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+				}
+			}
+		}
+		for i, j := 0, 0; i < len(ma) && j < len(mb); {
+			switch {
+			case ma[i] == mb[j]:
+				matches = append(matches, [2]int{prefix + i, prefix + j})
+				i++
+				j++
+			case lengths[i+1][j] >= lengths[i][j+1]:
+				i++
+			default:
+				j++
+			}
+		}
+	}
+
+	for n := suffix; n > 0; n-- {
+		matches = append(matches, [2]int{len(ka) - n, len(kb) - n})
+	}
+	return matches
+}
+
+// Append a key step to a path, escaping characters with a special meaning in
+// paths.
+func joinPath(path, key string) string {
+	var b strings.Builder
+	b.WriteString(path)
+	if path != "" {
+		b.WriteByte('.')
+	}
+	for _, ch := range []byte(key) {
+		if strings.IndexByte(`\.[]#*`, ch) >= 0 {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(ch)
+	}
+	return b.String()
+}
+
+// Append an index step to a path.
+func indexPath(path string, index int) string {
+	return path + "[" + strconv.Itoa(index) + "]"
+}
@@ -0,0 +1,97 @@
+package pot
+
+import (
+	"fmt"
+	"testing"
+)
+
+func ExampleDiff() {
+	oldNodes, _ := Parse([]byte(`{
+    name:  frontend
+    ports: [ 80 443 ]
+    route: { path: /api target: api }
+    route: { path: /    target: www }
+}`))
+	newNodes, _ := Parse([]byte(`{
+    ports: [ 80 8443 ]
+    name:  "frontend"
+    route: { path: /api target: api2 }
+    route: { path: /    target: www }
+    debug: true
+}`))
+	for _, change := range Diff(oldNodes, newNodes) {
+		fmt.Printf("%s (%s %s)\n", &change, change.OldLocation, change.NewLocation)
+	}
+	// Output:
+	// changed ports[1]: 443 -> 8443 (3:16 2:16)
+	// changed route#0.target: api -> api2 (4:32 4:32)
+	// added debug: true (1:0 6:11)
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		old, new string
+		changes  []string
+	}{
+		{"a", "\"a\"", nil},
+		{"a", "b", []string{"changed .: a -> b"}},
+		{"a", "[ a ]", []string{"changed .: a -> [ a ]"}},
+		{"a", "a b", []string{"added #1: b"}},
+		{"a b", "b", []string{"removed #0: a"}},
+		{"{ a: 1 } { b: 2 }", "{ a: 1 } { b: 3 }", []string{"changed #1.b: 2 -> 3"}},
+		{"[ a ] [ b ]", "[ a ] [ b c ]", []string{"added #1[1]: c"}},
+		{"{ a: b c: d }", "{ c: d a: b }", nil},
+		{"{ a: b a: c }", "{ a: b }", []string{"removed a#1: c"}},
+		{"{ a: b }", "{ a: b a: c }", []string{"added a#1: c"}},
+		{"{ a: b a: c }", "{ a: c }", []string{"changed a#0: b -> c", "removed a#1: c"}},
+		{"[ a b c d ]", "[ a c d ]", []string{"removed [1]: b"}},
+		{"[ a c d ]", "[ x a c y ]", []string{"added [0]: x", "changed [3]: d -> y"}},
+		{"[ a b ]", "[ c ]", []string{"changed [0]: a -> c", "removed [1]: b"}},
+		{"[ { a: b } ]", "[ { a: c } [ ] ]", []string{"changed [0].a: b -> c", "added [1]: [ ]"}},
+		{"[ q { a: 1 b: 2 } ]", "[ { c: 1 } q { a: 1 } ]", []string{"added [0]: { c: 1 }", "removed [1].b: 2"}},
+		{"[ q { a: 1 } ]", "[ x q { a: 2 } ]", []string{"added [0]: x", "changed [2].a: 1 -> 2"}},
+		{"a { b: 1 c: 2 }", "x a { b: 1 }", []string{"added #0: x", "removed #1.c: 2"}},
+	}
+	for _, test := range tests {
+		oldNodes, err := Parse([]byte(test.old))
+		if err != nil {
+			t.Fatal(err)
+		}
+		newNodes, err := Parse([]byte(test.new))
+		if err != nil {
+			t.Fatal(err)
+		}
+		changes := Diff(oldNodes, newNodes)
+		if len(changes) != len(test.changes) {
+			t.Errorf("Diff(%q, %q) = %v want %q", test.old, test.new, changes, test.changes)
+			continue
+		}
+		for i, change := range changes {
+			if s := change.String(); s != test.changes[i] {
+				t.Errorf("Diff(%q, %q)[%d] = %q want %q", test.old, test.new, i, s, test.changes[i])
+			}
+
+			// The path selects the changed value from the root level values.
+			nodes, node := newNodes, change.New
+			if change.Kind == Removed {
+				nodes, node = oldNodes, change.Old
+			}
+			path, err := CompilePath(change.Path)
+			if err != nil {
+				t.Errorf("Diff(%q, %q)[%d] path: %s", test.old, test.new, i, err)
+				continue
+			}
+			if selected := path.Select(nodes...); len(selected) != 1 || selected[0] != node {
+				t.Errorf("Diff(%q, %q)[%d] path %q selects %v", test.old, test.new, i, change.Path, selected)
+			}
+		}
+	}
+
+	// Keys with characters that have a special meaning in paths are escaped.
+	oldNode, newNode := NewDict(), NewDict()
+	oldNode.Append("a.b", NewString("c"))
+	newNode.Append("a.b", NewString("d"))
+	if changes := Diff([]*Node{oldNode}, []*Node{newNode}); len(changes) != 1 || changes[0].Path != `a\.b` {
+		t.Errorf("Diff() = %v want path %q", changes, `a\.b`)
+	}
+}
@@ -3,8 +3,8 @@ Package pot (Pieces of Text) provides a lightweight text serialization parser
 that is intended to be used together with Go's encoding.TextUnmarshaler
 interface.
 
-There is only de-serialization support, it's easy enough to generate properly
-formated POT directly from a encoding.Textmarshaler.
+Go values are converted to and from POT text using Unmarshal and Marshal, or
+by walking the parsers directly for full control.
 
 
 Format
@@ -27,6 +27,16 @@ limited similar to variable names in most programming languages. The characters
 a-z, A-Z, 0-9 are allowed in any position, the character '-' is allowed in any
 position but the first. Dictionary keys are delimited by values by ':'.
 
+Parsers created with ParserOptions.ExtendedKeys set accept an extended set of
+key characters to model existing configuration vocabularies. In addition to
+the standard characters, '_' is allowed in any position, '.' is allowed in any
+position but the first and Unicode letters and digits are allowed in any
+position. Marshal and the Node and Printer formatting functions accept extended
+keys as well. Text containing them is read back using ExtendedKeys.
+
+Comments start with '#' and extend to the end of the line. They may appear
+anywhere space may appear and are skipped by the parsers.
+
 
 Syntax Examples
 
@@ -49,19 +59,50 @@ Strings:
 	this-is-a-string
 	"this is a string"
 
+Comments:
+
+	# This is a comment.
+	{ fruit: orange # The price is per kg.
+	  price: 10.5 }
+
 Escape Codes
 
-The escape character is '\'. Characters '{', '}', '[', ']', ':', ' ' must be
-quoted or escaped in strings. Characters '\' and '"' must be escaped in
+The escape character is '\'. Characters '{', '}', '[', ']', ':', ' ', '#'
+must be quoted or escaped in strings. Characters '\' and '"' must be escaped in
 strings. Additionally '\n' produces a new-line, '\r' a carriage return and '\t'
-a tab.
+a tab. An escape code applies to the single character following '\' only, so an
+unquoted string still ends at the first unescaped delimiter after it.
 
 
 Usage
 
 Create a new root level parser and call parser.Next() until it returns nil or an
 error. There is also ParserScanner type that wraps a parser interface to provide
-a bufio.Scanner like API
+a bufio.Scanner like API. Parsers implement Spanner to report the span of their
+text in the input with start and end locations and byte offsets.
+
+Root, Dict and List parsers also provide All iterators for use with range loops.
+Dictionaries yield key and value pairs.
+
+Use NewReaderParser to parse root level values incrementally from an io.Reader.
+
+NewRecoveringParserScanner and ParseRecover continue past parse errors and
+collect all of them in an ErrorList. Parse errors have an ErrorKind that can be
+tested for with errors.Is.
+
+Use ErrorReport to show parse errors together with the offending source lines.
+
+Tools working on the token level, such as syntax highlighters, can use a Lexer
+to split text input into tokens including whitespace and comments. Walk delivers
+dictionaries, lists, keys and strings as events to a Handler without creating
+parsers, which is useful for filtering or converting large documents.
+
+Use Query or Node.Query to select values with path expressions such as
+"servers[2].ports", see Path for the syntax.
+
+Use NewParserWithOptions, NewReaderParserWithOptions or
+Decoder.SetParserOptions to parse untrusted input with limits on nesting depth,
+string length, number of dictionary entries or list values and input size.
 
 Example:
 
@@ -84,5 +125,37 @@ Example:
 			fmt.Printf("error: %s\n", err)
 		}
 	}
+
+Most applications are better served by Unmarshal which walks the parsers and
+fills in Go structs, maps, slices and encoding.TextUnmarshaler values:
+
+	var fruit struct {
+		Fruit string
+		Price float64
+	}
+	err := pot.Unmarshal([]byte("{ fruit: orange price: 10.5 }"), &fruit)
+
+Streams of root level values are read and written using Decoder and Encoder.
+
+Parse builds an in-memory document model of Node values that may be looked up
+repeatedly, modified and formatted back into POT text using FormatNodes.
+PrettyPrint and FormatNodes use a fixed layout, a Printer offers options for
+indentation, key alignment, line width and compact output. Its KeepLayout option
+formats the text input while keeping comments and blank lines.
+
+Canonical formats POT text in a canonical form where space, comments, quoting
+and escaping no longer matter. Hash and Equal build on it to detect semantic
+changes of documents.
+
+Diff compares documents structurally and reports the paths of added, removed
+and changed values.
+
+Merge merges the changes made to two versions of a document since their common
+ancestor, holding conflicting changes in conflict nodes that are formatted with
+conflict markers.
+
+Use ParseSyntax to edit POT text programmatically. It builds a syntax tree
+that keeps space and comments so that unmodified parts of the text are written
+back as is.
 */
 package pot
@@ -0,0 +1,314 @@
+package pot
+
+import (
+	"bytes"
+	"encoding"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// Error returned by Marshal when attempting to encode an unsupported value
+// type.
+type UnsupportedTypeError struct {
+	Type reflect.Type
+}
+
+// Implements error.
+func (err *UnsupportedTypeError) Error() string {
+	return "unsupported type " + err.Type.String()
+}
+
+// Error returned by Marshal when attempting to encode a value that can't be
+// represented in POT, such as a nil pointer or an invalid dictionary key.
+type UnsupportedValueError struct {
+	Value reflect.Value
+	Str   string
+}
+
+// Implements error.
+func (err *UnsupportedValueError) Error() string {
+	return "unsupported value " + err.Str
+}
+
+// Error returned by Marshal when a MarshalPOT or MarshalText method fails or
+// MarshalPOT returns invalid POT text.
+type MarshalerError struct {
+	Type reflect.Type
+	Err  error
+}
+
+// Implements error.
+func (err *MarshalerError) Error() string {
+	return "error marshaling type " + err.Type.String() + ": " + err.Err.Error()
+}
+
+// Returns the underlying error.
+func (err *MarshalerError) Unwrap() error {
+	return err.Err
+}
+
+// Marshaler is implemented by types that can marshal themselves into POT text.
+// MarshalPOT must return exactly one root level value.
+type Marshaler interface {
+	MarshalPOT() ([]byte, error)
+}
+
+var (
+	marshalerType     = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Marshal a Go value into POT text.
+//
+// Structs and maps are marshaled as dictionaries, slices and arrays as lists
+// and strings, booleans, numbers, byte slices and types implementing
+// encoding.TextMarshaler as strings. The output of values implementing
+// Marshaler is validated and reformatted to a single line. Strings are quoted
+// and escaped the same way as String.String() does it. Pointers and interfaces
+// are marshaled as the value they point to.
+//
+// Struct fields are named the same way as for Unmarshal. A field with the
+// "omitempty" option, as in `pot:"name,omitempty"`, is left out if it has an
+// empty value. Nil pointer and interface fields are always left out as there is
+// no POT representation for them. Map entries are sorted by key.
+//
+// The output is a single line of POT text similar to what the non-indented
+// parts of PrettyPrint produces.
+func Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encodeValue(&buf, reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Encoder writing POT values to an output stream.
+type Encoder struct {
+	w      io.Writer
+	pretty bool
+}
+
+// Create a new encoder writing to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Enable or disable pretty printing of encoded values using the PrettyPrint
+// layout.
+func (enc *Encoder) SetPrettyPrint(pretty bool) {
+	enc.pretty = pretty
+}
+
+// Write the POT encoding of v followed by a new-line to the stream.
+// Multiple encoded values form a stream of root level values that may be read
+// back using a root level parser.
+func (enc *Encoder) Encode(v interface{}) error {
+	buf, err := Marshal(v)
+	if err != nil {
+		return err
+	}
+	if enc.pretty {
+		// Marshal accepts extended keys, parse them back as such.
+		nodes, err := parseNodes(NewParserWithOptions(buf, &ParserOptions{ExtendedKeys: true}))
+		if err != nil {
+			return err
+		}
+		if buf, err = prettyPrinter.PrintNodes(nodes); err != nil {
+			return err
+		}
+	}
+	_, err = enc.w.Write(append(buf, '\n'))
+	return err
+}
+
+// Encode a Go value as POT text into buf.
+func encodeValue(buf *bytes.Buffer, v reflect.Value) error {
+	if !v.IsValid() {
+		return &UnsupportedValueError{v, "nil"}
+	}
+	for _, t := range []reflect.Type{marshalerType, textMarshalerType} {
+		m := v
+		if v.Kind() != reflect.Ptr && v.CanAddr() && reflect.PtrTo(v.Type()).Implements(t) {
+			m = v.Addr()
+		} else if !v.Type().Implements(t) {
+			continue
+		}
+		if m.Kind() == reflect.Ptr && m.IsNil() {
+			return &UnsupportedValueError{v, "nil pointer"}
+		}
+		switch m := m.Interface().(type) {
+		case Marshaler:
+			return encodeMarshaler(buf, m)
+		case encoding.TextMarshaler:
+			return encodeTextMarshaler(buf, m)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		buf.WriteString(formatString([]byte(v.String())))
+	case reflect.Bool:
+		buf.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		buf.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		buf.WriteString(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		buf.WriteString(strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()))
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			buf.WriteString(formatString(v.Bytes()))
+			return nil
+		}
+		return encodeList(buf, v)
+	case reflect.Array:
+		return encodeList(buf, v)
+	case reflect.Map:
+		return encodeMap(buf, v)
+	case reflect.Struct:
+		return encodeStruct(buf, v)
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return &UnsupportedValueError{v, "nil " + v.Kind().String()}
+		}
+		return encodeValue(buf, v.Elem())
+	default:
+		return &UnsupportedTypeError{v.Type()}
+	}
+	return nil
+}
+
+// Encode the output of a Marshaler into buf.
+// The output is validated and reformatted to a single line.
+func encodeMarshaler(buf *bytes.Buffer, m Marshaler) error {
+	pot, err := m.MarshalPOT()
+	if err != nil {
+		return &MarshalerError{reflect.TypeOf(m), err}
+	}
+	parser, err := scanSingleValue(pot)
+	if err != nil {
+		return &MarshalerError{reflect.TypeOf(m), err}
+	}
+	var node Node
+	if err = node.UnmarshalPOT(parser); err == nil {
+		err = node.write(buf)
+	}
+	if err != nil {
+		return &MarshalerError{reflect.TypeOf(m), err}
+	}
+	return nil
+}
+
+// Encode a TextMarshaler as a POT string into buf.
+func encodeTextMarshaler(buf *bytes.Buffer, m encoding.TextMarshaler) error {
+	text, err := m.MarshalText()
+	if err != nil {
+		return &MarshalerError{reflect.TypeOf(m), err}
+	}
+	buf.WriteString(formatString(text))
+	return nil
+}
+
+// Encode a slice or array as a POT list into buf.
+func encodeList(buf *bytes.Buffer, v reflect.Value) error {
+	buf.WriteString("[ ")
+	for i := 0; i < v.Len(); i++ {
+		if err := encodeValue(buf, v.Index(i)); err != nil {
+			return err
+		}
+		buf.WriteByte(' ')
+	}
+	buf.WriteByte(']')
+	return nil
+}
+
+// Encode a map as a POT dictionary into buf.
+func encodeMap(buf *bytes.Buffer, v reflect.Value) error {
+	type entry struct {
+		key   string
+		value reflect.Value
+	}
+
+	var entries []entry
+	iter := v.MapRange()
+	for iter.Next() {
+		k := iter.Key()
+		var key string
+		if m, ok := k.Interface().(encoding.TextMarshaler); ok {
+			text, err := m.MarshalText()
+			if err != nil {
+				return &MarshalerError{k.Type(), err}
+			}
+			key = string(text)
+		} else if k.Kind() == reflect.String {
+			key = k.String()
+		} else {
+			return &UnsupportedTypeError{v.Type()}
+		}
+		entries = append(entries, entry{key, iter.Value()})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	buf.WriteString("{ ")
+	for _, e := range entries {
+		if err := encodeEntry(buf, e.key, e.value); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// Encode a struct as a POT dictionary into buf.
+func encodeStruct(buf *bytes.Buffer, v reflect.Value) error {
+	buf.WriteString("{ ")
+	for _, f := range cachedFields(v.Type()) {
+		fv := v.FieldByIndex(f.index)
+		if f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		if (fv.Kind() == reflect.Ptr || fv.Kind() == reflect.Interface) && fv.IsNil() {
+			continue
+		}
+		if err := encodeEntry(buf, f.name, fv); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+// Encode a dictionary entry into buf.
+func encodeEntry(buf *bytes.Buffer, key string, v reflect.Value) error {
+	if !validKey([]byte(key), true) {
+		return &UnsupportedValueError{reflect.ValueOf(key), "dictionary key " + strconv.Quote(key)}
+	}
+	buf.WriteString(key)
+	buf.WriteString(": ")
+	if err := encodeValue(buf, v); err != nil {
+		return err
+	}
+	buf.WriteByte(' ')
+	return nil
+}
+
+// Check if v is the zero value of its kind as far as omitempty is concerned.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package pot
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func ExampleMarshal() {
+	legs := 4
+	buf, err := Marshal(testAnimal{
+		Animal:      "zebra",
+		Class:       "mammal",
+		WeightRange: []string{"240kg", "370kg"},
+		Foods:       map[string]float64{"grass": 0.9, "apples": 0.1},
+		Legs:        &legs,
+		Address:     net.IPv4(127, 0, 0, 1),
+	})
+	if err != nil {
+		fmt.Printf("error: %s\n", err)
+		return
+	}
+	fmt.Printf("%s\n", buf)
+	// Output:
+	// { Animal: zebra Class: mammal weight-range: [ 240kg 370kg ] Foods: { apples: 0.1 grass: 0.9 } Legs: 4 Address: 127.0.0.1 }
+}
+
+func ExampleEncoder() {
+	enc := NewEncoder(os.Stdout)
+	enc.SetPrettyPrint(true)
+	for _, v := range []interface{}{
+		"a string",
+		[]interface{}{"a", "list", map[string]string{"with": "dictionary"}},
+		struct {
+			Key   string
+			Empty string `pot:"empty,omitempty"`
+			Dict  struct{ A, B string }
+		}{Key: "{value}", Dict: struct{ A, B string }{"a", "b"}},
+	} {
+		if err := enc.Encode(v); err != nil {
+			fmt.Printf("error: %s\n", err)
+		}
+	}
+	// Output:
+	// "a string"
+	// [ a list { with: dictionary } ]
+	// {
+	//     Key: "{value}"
+	//     Dict: {
+	//         A: a
+	//         B: b
+	//     }
+	// }
+}
+
+func TestMarshal_RoundTrip(t *testing.T) {
+	in := testOuter{
+		testEmbedded: testEmbedded{A: "a\n\"b\""},
+		B:            "",
+		Array:        [2]int{-1, 2},
+		Any:          map[string]interface{}{"x": []interface{}{"y", "a b", "c\td e"}},
+		Bytes:        []byte("b:y:t:e:s"),
+		Bool:         true,
+		Uint:         255,
+		Keys:         map[testKey]string{"key-k": "v"},
+	}
+	buf, err := Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out testOuter
+	if err = Unmarshal(buf, &out); err != nil {
+		t.Fatal(err)
+	}
+	// Keys are prefixed by testKey.UnmarshalText.
+	out.Keys = map[testKey]string{"key-k": out.Keys["key-key-k"]}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("Unmarshal(Marshal()) = %+v want %+v", out, in)
+	}
+}
+
+func TestMarshal_Errors(t *testing.T) {
+	tests := []struct {
+		v   interface{}
+		err string
+	}{
+		{nil, "unsupported value nil"},
+		{[]*int{nil}, "unsupported value nil ptr"},
+		{make(chan int), "unsupported type chan int"},
+		{map[int]string{1: "a"}, "unsupported type map[int]string"},
+		{map[string]string{"-a": "a"}, "unsupported value dictionary key \"-a\""},
+		{struct {
+			A string `pot:"a b"`
+		}{}, "unsupported value dictionary key \"a b\""},
+	}
+	for _, test := range tests {
+		if _, err := Marshal(test.v); err == nil || err.Error() != test.err {
+			t.Errorf("Marshal(%#v) = %v want %s", test.v, err, test.err)
+		}
+	}
+}
+
+type testBadMarshaler struct{}
+
+func (testBadMarshaler) MarshalPOT() ([]byte, error) {
+	return []byte("{ a: }"), nil
+}
+
+// Test that extended keys are marshaled and read back using ExtendedKeys.
+func TestMarshal_ExtendedKeys(t *testing.T) {
+	type server struct {
+		Name string `pot:"server_name"`
+	}
+	in := server{"a"}
+	buf, err := Marshal(in)
+	if want := "{ server_name: a }"; err != nil || string(buf) != want {
+		t.Errorf("Marshal() = (%s, %v) want %s", buf, err, want)
+	}
+
+	var out bytes.Buffer
+	enc := NewEncoder(&out)
+	enc.SetPrettyPrint(true)
+	if err = enc.Encode(in); err != nil {
+		t.Fatal(err)
+	}
+	dec := NewDecoder(&out)
+	dec.SetParserOptions(&ParserOptions{ExtendedKeys: true})
+	var got server
+	if err = dec.Decode(&got); err != nil || got != in {
+		t.Errorf("Decode() = (%+v, %v) want %+v", got, err, in)
+	}
+}
+
+func TestMarshal_Marshaler(t *testing.T) {
+	buf, err := Marshal(map[string]interface{}{"points": []testPoint{{1, 2, Location{}}, {3, 4, Location{}}}})
+	if want := "{ points: [ [ 1 2 ] [ 3 4 ] ] }"; err != nil || string(buf) != want {
+		t.Errorf("Marshal() = (%s, %v) want %s", buf, err, want)
+	}
+
+	_, err = Marshal([]testBadMarshaler{{}})
+	if want := "error marshaling type *pot.testBadMarshaler: 1:5: key without value in dictionary"; err == nil || err.Error() != want {
+		t.Errorf("Marshal() = %v want %s", err, want)
+	}
+}
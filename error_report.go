@@ -0,0 +1,116 @@
+package pot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// ANSI escape codes used by colored error reports.
+const (
+	ansiBold  = "\x1b[1m"
+	ansiRed   = "\x1b[31m"
+	ansiReset = "\x1b[0m"
+)
+
+// Renders parse errors together with the offending source line and a caret
+// pointing at the error location.
+//
+// Example output:
+//
+//	config.pot:3:6: invalid character ':' in string
+//	    3 |   d: e:f
+//	      |       ^
+type ErrorReport struct {
+	Source  []byte // Text input the errors refer to.
+	Context int    // Number of source lines to show before the offending line.
+	Color   bool   // Highlight the message and caret using ANSI escape codes.
+}
+
+// Render a report of err.
+// See Write for details.
+func (report *ErrorReport) Render(err error) string {
+	var buf bytes.Buffer
+	report.Write(&buf, err)
+	return buf.String()
+}
+
+// Write a report of err to w.
+// Every parse error of an ErrorList is reported in order. Errors that are not
+// parse errors are written as is on a line of their own.
+func (report *ErrorReport) Write(w io.Writer, err error) error {
+	var list ErrorList
+	var perr *ParseError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &list):
+	case errors.As(err, &perr):
+		list = ErrorList{perr}
+	default:
+		_, werr := fmt.Fprintf(w, "%s\n", err)
+		return werr
+	}
+
+	var buf bytes.Buffer
+	lines := bytes.Split(report.Source, []byte("\n"))
+	for _, perr := range list {
+		report.writeError(&buf, perr, lines)
+	}
+	_, werr := w.Write(buf.Bytes())
+	return werr
+}
+
+// Write a report of a single parse error to buf.
+// Lines are the lines of the source text.
+func (report *ErrorReport) writeError(buf *bytes.Buffer, err *ParseError, lines [][]byte) {
+	if report.Color {
+		fmt.Fprintf(buf, "%s%s%s\n", ansiBold, err, ansiReset)
+	} else {
+		fmt.Fprintf(buf, "%s\n", err)
+	}
+
+	errLine := int(err.Location.Line)
+	if errLine >= len(lines) {
+		return
+	}
+	first := errLine - report.Context
+	if first < 0 {
+		first = 0
+	}
+	width := len(fmt.Sprint(errLine + 1))
+	for i := first; i <= errLine; i++ {
+		fmt.Fprintf(buf, "    %*d | %s\n", width, i+1, bytes.TrimSuffix(lines[i], []byte("\r")))
+	}
+
+	caret := "^"
+	if report.Color {
+		caret = ansiRed + caret + ansiReset
+	}
+	fmt.Fprintf(buf, "    %*s | %s%s\n", width, "", caretIndent(lines[errLine], err.Location.Column), caret)
+}
+
+// Get the indentation placing a caret below the specified column of line.
+// Columns are counted the same way as by Location.updateFromBytes. Tabs are
+// kept to make the caret line up with the source line when displayed.
+func caretIndent(line []byte, column uint32) []byte {
+	if i := bytes.LastIndexByte(bytes.TrimSuffix(line, []byte("\r")), '\r'); i >= 0 {
+		line = line[i+1:] // Carriage returns reset the column count.
+	}
+	var indent []byte
+	for n := uint32(0); n < column && len(line) > 0; n++ {
+		if line[0] == '\t' {
+			indent = append(indent, '\t')
+		} else {
+			indent = append(indent, ' ')
+		}
+		_, size := utf8.DecodeRune(line)
+		line = line[size:]
+	}
+	for n := uint32(len(indent)); n < column; n++ {
+		indent = append(indent, ' ')
+	}
+	return indent
+}
@@ -0,0 +1,47 @@
+package pot
+
+import (
+	"fmt"
+	"testing"
+)
+
+func ExampleErrorReport() {
+	src := []byte("{\n  a: b\n  c: d:e\n}\n")
+	_, err := Parse(src)
+	report := ErrorReport{Source: src, Context: 1}
+	fmt.Print(report.Render(err))
+	// Output:
+	// 3:6: invalid character ':' in string
+	//     2 |   a: b
+	//     3 |   c: d:e
+	//       |       ^
+}
+
+func TestErrorReport(t *testing.T) {
+	tests := []struct {
+		src    string
+		err    error
+		report string
+	}{
+		{"\t{ å: x:y }", Location{0, 7}.Errorf("e"), "1:7: e\n    1 | \t{ å: x:y }\n      | \t      ^\n"},
+		{"a\r\nb\r\n", Location{1, 1}.Errorf("e"), "2:1: e\n    2 | b\n      |  ^\n"},
+		{"ab", Location{0, 4}.Errorf("e"), "1:4: e\n    1 | ab\n      |     ^\n"},
+		{"ab", Location{3, 0}.Errorf("e"), "4:0: e\n"},
+		{"ab", fmt.Errorf("other"), "other\n"},
+		{"ab", nil, ""},
+		{"a\nb", ErrorList{Location{0, 0}.Errorf("e1"), Location{1, 1}.Errorf("e2")},
+			"1:0: e1\n    1 | a\n      | ^\n2:1: e2\n    2 | b\n      |  ^\n"},
+	}
+	for _, test := range tests {
+		report := ErrorReport{Source: []byte(test.src)}
+		if s := report.Render(test.err); s != test.report {
+			t.Errorf("Render(%q) = %q want %q", test.err, s, test.report)
+		}
+	}
+
+	report := ErrorReport{Source: []byte("ab"), Color: true}
+	want := "\x1b[1m1:1: e\x1b[0m\n    1 | ab\n      |  \x1b[31m^\x1b[0m\n"
+	if s := report.Render(Location{0, 1}.Errorf("e")); s != want {
+		t.Errorf("Render() = %q want %q", s, want)
+	}
+}
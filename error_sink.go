@@ -3,16 +3,26 @@ package pot
 // Error sink used to implement "abort on first error" functionality with ease.
 // A function would check if the sink already contains an error and if it does
 // do nothing.
+//
+// A recovering sink instead collects parse errors in a list and only aborts on
+// other errors.
 type errorSink struct {
-	e error
+	e       error
+	recover bool      // Collect parse errors instead of aborting on them.
+	list    ErrorList // Collected parse errors.
 }
 
 // Returns the error stored in the sink.
+// A recovering sink returns the collected parse errors as an ErrorList unless
+// it has aborted on another error.
 func (p *errorSink) err() error {
+	if p.e == nil {
+		return p.list.Err()
+	}
 	return p.e
 }
 
-// Returns true if there is no error in the sink.
+// Returns true if there is no error in the sink that aborts processing.
 func (p *errorSink) ok() bool {
 	if p.e == nil {
 		return true
@@ -21,9 +31,12 @@ func (p *errorSink) ok() bool {
 }
 
 // Sends an error to the sink.
-// Does nothing if an error is already stored in the sink.
+// Does nothing if an error is already stored in the sink. A recovering sink
+// adds parse errors to its list.
 func (p *errorSink) send(err error) {
-	if p.e == nil {
+	if perr, ok := err.(*ParseError); ok && p.recover && p.e == nil {
+		p.list = append(p.list, perr)
+	} else if p.e == nil {
 		p.e = err
 	}
 }
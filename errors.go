@@ -2,10 +2,60 @@ package pot
 
 import "fmt"
 
+// Kind of parse error.
+// Error kinds are used as sentinel values with errors.Is to tell different
+// errors apart without inspecting messages:
+//
+//	if errors.Is(err, pot.ErrInvalidKey) {
+//		...
+//	}
+type ErrorKind int
+
+const (
+	ErrOther             ErrorKind = iota // Error without a more specific kind.
+	ErrUnterminatedBlock                  // End of input inside a dictionary or list.
+	ErrInvalidKey                         // Malformed dictionary key.
+	ErrMissingValue                       // Dictionary key or document without a value.
+	ErrInvalidString                      // Invalid character in string.
+	ErrInvalidEscape                      // Invalid or unterminated escape code.
+	ErrUnterminatedQuote                  // Miss-matched quotes in string.
+	ErrUnexpectedValue                    // Unexpected value after a single value document.
+	ErrUnmarshal                          // Value that can not be unmarshaled into a Go value.
+	ErrDepthLimit                         // Nesting depth limit exceeded.
+	ErrStringLimit                        // String length limit exceeded.
+	ErrEntryLimit                         // Dictionary or list entry limit exceeded.
+	ErrInputLimit                         // Input size limit exceeded.
+)
+
+var errorKindNames = [...]string{
+	ErrOther:             "parse error",
+	ErrUnterminatedBlock: "unterminated block",
+	ErrInvalidKey:        "invalid key",
+	ErrMissingValue:      "missing value",
+	ErrInvalidString:     "invalid string",
+	ErrInvalidEscape:     "invalid escape code",
+	ErrUnterminatedQuote: "unterminated quote",
+	ErrUnexpectedValue:   "unexpected value",
+	ErrUnmarshal:         "cannot unmarshal",
+	ErrDepthLimit:        "depth limit exceeded",
+	ErrStringLimit:       "string limit exceeded",
+	ErrEntryLimit:        "entry limit exceeded",
+	ErrInputLimit:        "input limit exceeded",
+}
+
+// Implements error.
+func (kind ErrorKind) Error() string {
+	if kind >= 0 && int(kind) < len(errorKindNames) {
+		return errorKindNames[kind]
+	}
+	return fmt.Sprintf("error kind %d", int(kind))
+}
+
 // Parse error containing location information and optional identifier (file name or similar).
 type ParseError struct {
 	Identifier string
 	Location   Location
+	Kind       ErrorKind
 	Message    string
 }
 
@@ -16,3 +66,42 @@ func (err *ParseError) Error() string {
 	}
 	return fmt.Sprintf("%s: %s", &err.Location, err.Message)
 }
+
+// Reports whether target is the error kind of err.
+// Used by errors.Is.
+func (err *ParseError) Is(target error) bool {
+	kind, ok := target.(ErrorKind)
+	return ok && kind == err.Kind
+}
+
+// List of parse errors collected by parsers recovering from errors.
+type ErrorList []*ParseError
+
+// Implements error.
+// The first error is reported together with the number of additional errors.
+func (list ErrorList) Error() string {
+	switch len(list) {
+	case 0:
+		return "no errors"
+	case 1:
+		return list[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", list[0], len(list)-1)
+}
+
+// Returns the errors of the list for use by errors.Is and errors.As.
+func (list ErrorList) Unwrap() []error {
+	errs := make([]error, len(list))
+	for i, err := range list {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Returns the list as an error or nil if the list is empty.
+func (list ErrorList) Err() error {
+	if len(list) == 0 {
+		return nil
+	}
+	return list
+}
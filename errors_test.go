@@ -0,0 +1,74 @@
+package pot
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrorList(t *testing.T) {
+	err1 := &ParseError{Message: "first"}
+	err2 := &ParseError{Message: "second"}
+	tests := []struct {
+		list ErrorList
+		err  string
+	}{
+		{nil, "no errors"},
+		{ErrorList{err1}, "1:0: first"},
+		{ErrorList{err1, err2}, "1:0: first (and 1 more errors)"},
+	}
+	for _, test := range tests {
+		if s := test.list.Error(); s != test.err {
+			t.Errorf("Error() = %s want %s", s, test.err)
+		}
+	}
+	if ErrorList(nil).Err() != nil {
+		t.Errorf("Err() of empty list is not nil")
+	}
+	var err error = ErrorList{err1, err2}
+	if !errors.Is(err, err2) {
+		t.Errorf("errors.Is() = false want true")
+	}
+	if joined := errors.Join(ErrorList{err1}.Unwrap()...); joined.Error() != "1:0: first" {
+		t.Errorf("errors.Join() = %s", joined)
+	}
+}
+
+func TestErrorKind(t *testing.T) {
+	tests := []struct {
+		input string
+		kind  ErrorKind
+	}{
+		{"[ a [ b ]", ErrUnterminatedBlock},
+		{"{ a: b", ErrUnterminatedBlock},
+		{"{ -a: b }", ErrInvalidKey},
+		{"{ a b: c }", ErrInvalidKey},
+		{"{ a: }", ErrMissingValue},
+		{"a:b", ErrInvalidString},
+		{"a\\m", ErrInvalidEscape},
+		{"a\\", ErrInvalidEscape},
+		{"\"a", ErrUnterminatedQuote},
+	}
+	for _, test := range tests {
+		_, err := Parse([]byte(test.input))
+		if !errors.Is(err, test.kind) {
+			t.Errorf("Parse(%q) = %v want kind %q", test.input, err, test.kind)
+		}
+		if _, serr := ParseSyntax([]byte(test.input)); !errors.Is(serr, test.kind) {
+			t.Errorf("ParseSyntax(%q) = %v want kind %q", test.input, serr, test.kind)
+		}
+	}
+
+	var v []int
+	if err := Unmarshal([]byte("[ 1 a ]"), &v); !errors.Is(err, ErrUnmarshal) {
+		t.Errorf("Unmarshal() = %v want kind %q", err, ErrUnmarshal)
+	}
+	if err := Unmarshal([]byte("[] []"), &v); !errors.Is(err, ErrUnexpectedValue) {
+		t.Errorf("Unmarshal() = %v want kind %q", err, ErrUnexpectedValue)
+	}
+	if _, err := ParseRecover([]byte("[ a:b c\\m ]")); !errors.Is(err, ErrInvalidEscape) || errors.Is(err, ErrInvalidKey) {
+		t.Errorf("ParseRecover() = %v", err)
+	}
+	if ErrorKind(100).Error() != "error kind 100" {
+		t.Errorf("Error() = %s", ErrorKind(100))
+	}
+}
@@ -0,0 +1,102 @@
+package pot
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Struct field information used when mapping dictionary keys to struct fields.
+type field struct {
+	name      string // Dictionary key name.
+	index     []int  // Index sequence for reflect.Value.FieldByIndex.
+	omitEmpty bool   // Omit the field when marshaling if it has an empty value.
+}
+
+// Cache of struct fields indexed by reflect.Type.
+var fieldCache sync.Map
+
+// Get the fields of struct type t, computing them on first use.
+func cachedFields(t reflect.Type) []field {
+	if f, ok := fieldCache.Load(t); ok {
+		return f.([]field)
+	}
+	f, _ := fieldCache.LoadOrStore(t, dominantFields(typeFields(t)))
+	return f.([]field)
+}
+
+// Remove fields hidden by a field with the same name closer to the outer
+// struct, keeping the first field if there are several at the same depth.
+func dominantFields(fields []field) []field {
+	var out []field
+	for i, f := range fields {
+		hidden := false
+		for j, g := range fields {
+			if g.name == f.name && (len(g.index) < len(f.index) || len(g.index) == len(f.index) && j < i) {
+				hidden = true
+				break
+			}
+		}
+		if !hidden {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// Collect the fields of struct type t.
+// Fields of untagged embedded structs are promoted to the outer struct.
+func typeFields(t reflect.Type) []field {
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("pot")
+		if tag == "-" {
+			continue
+		}
+		name, opts := parseTag(tag)
+		if sf.Anonymous && name == "" && sf.Type.Kind() == reflect.Struct {
+			for _, f := range typeFields(sf.Type) {
+				f.index = append([]int{i}, f.index...)
+				fields = append(fields, f)
+			}
+			continue
+		}
+		if sf.PkgPath != "" {
+			continue // Unexported field.
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, field{
+			name:      name,
+			index:     []int{i},
+			omitEmpty: opts == "omitempty",
+		})
+	}
+	return fields
+}
+
+// Split a struct field tag into its name and options.
+func parseTag(tag string) (string, string) {
+	if i := strings.IndexByte(tag, ','); i >= 0 {
+		return tag[:i], tag[i+1:]
+	}
+	return tag, ""
+}
+
+// Find the field matching key.
+// An exact match is preferred over a case insensitive match.
+func findField(fields []field, key string) *field {
+	for i := range fields {
+		if fields[i].name == key {
+			return &fields[i]
+		}
+	}
+	for i := range fields {
+		if strings.EqualFold(fields[i].name, key) {
+			return &fields[i]
+		}
+	}
+	return nil
+}
@@ -1 +1,3 @@
 module github.com/johan-bolmsjo/pot
+
+go 1.23
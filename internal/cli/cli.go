@@ -0,0 +1,68 @@
+// Package cli provides the file handling and error reporting shared by the POT
+// commands.
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/johan-bolmsjo/pot"
+)
+
+// Name of the standard input in messages.
+const Stdin = "<stdin>"
+
+// Read the text of a file or standard input if the name is "-".
+// Returns the text and the name of the file to use in messages.
+func ReadFile(name string) ([]byte, string, error) {
+	if name == "-" {
+		buf, err := io.ReadAll(os.Stdin)
+		return buf, Stdin, err
+	}
+	buf, err := os.ReadFile(name)
+	return buf, name, err
+}
+
+// Read and parse a file or standard input if the name is "-".
+// Exits on errors.
+func ParseFile(name string) []*pot.Node {
+	buf, name, err := ReadFile(name)
+	if err != nil {
+		Fatalf("Failed to read file, %s\n", err)
+	}
+	return Parse(name, buf)
+}
+
+// Parse the text of a file. Exits with an error report on parse errors.
+func Parse(name string, buf []byte) []*pot.Node {
+	nodes, err := pot.Parse(buf)
+	if err != nil {
+		Fatalf("Failed to parse POT, %s", Render(name, buf, err))
+	}
+	return nodes
+}
+
+// Render an error of the text of a file as an error report, colored if
+// standard error is a terminal. Parse errors are identified by the file name.
+func Render(name string, buf []byte, err error) string {
+	var perr *pot.ParseError
+	if errors.As(err, &perr) {
+		perr.Identifier = name
+	}
+	report := pot.ErrorReport{Source: buf, Color: IsTerminal(os.Stderr)}
+	return report.Render(err)
+}
+
+// Check if file is a terminal.
+func IsTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Print a message to standard error and exit with status 2.
+func Fatalf(format string, a ...interface{}) {
+	fmt.Fprintf(os.Stderr, format, a...)
+	os.Exit(2)
+}
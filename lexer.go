@@ -0,0 +1,253 @@
+package pot
+
+import (
+	"bytes"
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Kind of lexical token.
+type TokenKind int
+
+const (
+	TokenEOF          TokenKind = iota // End of input.
+	TokenWhitespace                    // Space, tabs and new-lines.
+	TokenComment                       // Comment from '#' to the end of the line.
+	TokenBraceOpen                     // Dictionary start '{'.
+	TokenBraceClose                    // Dictionary end '}'.
+	TokenBracketOpen                   // List start '['.
+	TokenBracketClose                  // List end ']'.
+	TokenKey                           // Dictionary key including the ':' delimiter.
+	TokenString                        // Quoted or unquoted string.
+)
+
+var tokenKindNames = [...]string{
+	TokenEOF:          "end of input",
+	TokenWhitespace:   "whitespace",
+	TokenComment:      "comment",
+	TokenBraceOpen:    "'{'",
+	TokenBraceClose:   "'}'",
+	TokenBracketOpen:  "'['",
+	TokenBracketClose: "']'",
+	TokenKey:          "key",
+	TokenString:       "string",
+}
+
+// Implements fmt.Stringer.
+func (kind TokenKind) String() string {
+	if kind >= 0 && int(kind) < len(tokenKindNames) {
+		return tokenKindNames[kind]
+	}
+	return fmt.Sprintf("token kind %d", int(kind))
+}
+
+// Lexical token.
+type Token struct {
+	Kind     TokenKind
+	Raw      []byte   // Raw text of the token.
+	Value    []byte   // Key name without ':' or string value with escape codes and quotes evaluated.
+	Location Location // Token start location in the text input.
+	Offset   int      // Token start byte offset in the text input.
+}
+
+// Get the span of the token text in the text input.
+func (tok *Token) Span() Span {
+	return Span{tok.Location, tok.byteLocation(len(tok.Raw)), tok.Offset, tok.Offset + len(tok.Raw)}
+}
+
+// Lexer splitting text input into tokens, including the whitespace and
+// comments between them. It uses the same rules as the parsers do, which makes
+// it suitable for syntax highlighters, formatters and linters.
+//
+// The lexer does not check the structure of the text input and does not
+// validate the characters of keys, that is left to the parsers.
+type Lexer struct {
+	buf parserBuf // Remaining text input.
+}
+
+// Create a new lexer splitting the supplied text into tokens.
+func NewLexer(pot []byte) *Lexer {
+	return newLexer(newParserBuf(pot))
+}
+
+// Create a new lexer operating on the supplied parser buffer.
+func newLexer(buf *parserBuf) *Lexer {
+	return &Lexer{*buf}
+}
+
+// Get the next token, a token of kind TokenEOF at end of input or an error.
+// The token is valid even if there is an error, it is only the evaluated
+// value of a string that is incomplete. Lexing may continue after errors.
+func (lex *Lexer) Next() (Token, error) {
+	buf := &lex.buf
+	tok := Token{Location: buf.location, Offset: buf.offset}
+	if len(buf.bytes) == 0 {
+		return tok, nil
+	}
+
+	n := 1
+	switch c := buf.bytes[0]; c {
+	case '{':
+		tok.Kind = TokenBraceOpen
+	case '}':
+		tok.Kind = TokenBraceClose
+	case '[':
+		tok.Kind = TokenBracketOpen
+	case ']':
+		tok.Kind = TokenBracketClose
+	case '#':
+		tok.Kind = TokenComment
+		if n = bytes.IndexByte(buf.bytes, '\n'); n == -1 {
+			n = len(buf.bytes)
+		}
+	default:
+		if r, _ := utf8.DecodeRune(buf.bytes); !unicode.IsSpace(r) {
+			return lex.scanWord()
+		}
+		tok.Kind = TokenWhitespace
+		if n = bytes.IndexFunc(buf.bytes, func(r rune) bool { return !unicode.IsSpace(r) }); n == -1 {
+			n = len(buf.bytes)
+		}
+	}
+	tok.Raw = buf.bytes[:n]
+	buf.trimBytesLeft(n)
+	return tok, nil
+}
+
+// Scans a key or string token.
+// Escape codes and quotes are evaluated while scanning so that the text only
+// has to be processed once.
+func (lex *Lexer) scanWord() (Token, error) {
+	buf := &lex.buf
+	tok := Token{Kind: TokenString, Location: buf.location, Offset: buf.offset}
+
+	// Fast path for quoted strings without escape codes, the value is then a
+	// part of the text input.
+	if buf.bytes[0] == '"' {
+		if n := bytes.IndexAny(buf.bytes[1:], "\\\""); n >= 0 && buf.bytes[n+1] == '"' {
+			if n += 2; n == len(buf.bytes) || isStringDelimiter(buf.bytes[n]) {
+				tok.Raw, tok.Value = buf.bytes[:n], buf.bytes[1:n-1]
+				buf.trimBytesLeft(n)
+				return tok, nil
+			}
+		}
+	}
+
+	quoted := false
+	escaped := false
+	decoded := false // Set when value differs from the raw text.
+	var value []byte
+	var valueErr error
+
+	i := 0
+loop:
+	for ; i < len(buf.bytes); i++ {
+		c := buf.bytes[i]
+		switch {
+		case escaped:
+			escaped = false
+			switch c {
+			case 'n', 'r', 't':
+				c = escapeCodeToChar[c]
+			case '\\', '"', '{', '}', '[', ']', ':', ' ', '#':
+			default:
+				if valueErr == nil {
+					location := buf.location
+					location.updateFromBytes(buf.bytes[:i])
+					valueErr = location.errorf(ErrInvalidEscape, "invalid escape code \\%c", c)
+				}
+			}
+		case c == '\\' || c == '"':
+			if !decoded {
+				value = append(make([]byte, 0, i), buf.bytes[:i]...)
+				decoded = true
+			}
+			if c == '\\' {
+				escaped = true
+			} else {
+				quoted = !quoted
+			}
+			continue
+		case quoted:
+		case c == ':':
+			tok.Kind = TokenKey
+			i++
+			break loop
+		case isStringDelimiter(c):
+			break loop
+		}
+		if decoded {
+			value = append(value, c)
+		}
+	}
+
+	tok.Raw = buf.bytes[:i]
+	buf.trimBytesLeft(i)
+	if tok.Kind == TokenKey {
+		tok.Value = tok.Raw[:i-1]
+		return tok, nil
+	}
+
+	if decoded {
+		tok.Value = value
+	} else {
+		tok.Value = tok.Raw
+	}
+	switch {
+	case valueErr != nil:
+		return tok, valueErr
+	case quoted:
+		return tok, buf.errorf(ErrUnterminatedQuote, "miss-matched quotes in string")
+	case escaped:
+		return tok, buf.errorf(ErrInvalidEscape, "unterminated escape code in string")
+	}
+	return tok, nil
+}
+
+// Create an error for a token that is not a valid dictionary key.
+// The text input following the token is used to report the character that
+// ended a string token prematurely. Closed is set if the token is directly
+// followed by the brace closing the dictionary.
+func keyError(tok Token, after *parserBuf, closed bool) error {
+	extended := after.extendedKeys()
+	switch tok.Kind {
+	case TokenKey:
+		if i := invalidKeyIndex(tok.Value, extended); i >= 0 {
+			r, _ := utf8.DecodeRune(tok.Value[i:])
+			return tok.byteLocation(i).errorf(ErrInvalidKey, "invalid character '%c' in key", r)
+		}
+		return tok.byteLocation(len(tok.Value)).errorf(ErrInvalidKey, "invalid character ':' in key")
+	case TokenString:
+		if i := invalidKeyIndex(tok.Raw, extended); i >= 0 {
+			r, _ := utf8.DecodeRune(tok.Raw[i:])
+			return tok.byteLocation(i).errorf(ErrInvalidKey, "invalid character '%c' in key", r)
+		}
+		if len(after.bytes) > 0 && !(closed && after.bytes[0] == '}') {
+			return after.errorf(ErrInvalidKey, "invalid character '%c' in key", after.bytes[0])
+		}
+		return after.errorf(ErrInvalidKey, "end of input while parsing key")
+	}
+	return tok.Location.errorf(ErrInvalidKey, "invalid character '%c' in key", tok.Raw[0])
+}
+
+// Get the location of byte i of the token text.
+func (tok *Token) byteLocation(i int) Location {
+	location := tok.Location
+	location.updateFromBytes(tok.Raw[:i])
+	return location
+}
+
+// Get the byte index of the first invalid character in key or -1 if all
+// characters are valid. An empty key has no invalid characters but is still
+// invalid.
+func invalidKeyIndex(key []byte, extended bool) int {
+	for i := 0; i < len(key); {
+		r, size := utf8.DecodeRune(key[i:])
+		if !validKeyRune(i, r, extended) {
+			return i
+		}
+		i += size
+	}
+	return -1
+}
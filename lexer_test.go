@@ -0,0 +1,68 @@
+package pot
+
+import (
+	"fmt"
+	"testing"
+)
+
+func ExampleLexer() {
+	lex := NewLexer([]byte("{ a: \"x y\" } # c"))
+	for {
+		tok, err := lex.Next()
+		if err != nil {
+			fmt.Println(err)
+		}
+		if tok.Kind == TokenEOF {
+			break
+		}
+		fmt.Printf("%s: %s %q %q\n", tok.Span(), tok.Kind, tok.Raw, tok.Value)
+	}
+	// Output:
+	// 1:0-1:1: '{' "{" ""
+	// 1:1-1:2: whitespace " " ""
+	// 1:2-1:4: key "a:" "a"
+	// 1:4-1:5: whitespace " " ""
+	// 1:5-1:10: string "\"x y\"" "x y"
+	// 1:10-1:11: whitespace " " ""
+	// 1:11-1:12: '}' "}" ""
+	// 1:12-1:13: whitespace " " ""
+	// 1:13-1:16: comment "# c" ""
+}
+
+func TestLexer(t *testing.T) {
+	tests := []struct {
+		src   string
+		kinds []TokenKind
+		err   string
+	}{
+		{"", nil, ""},
+		{"[a\\ b]\n", []TokenKind{TokenBracketOpen, TokenString, TokenBracketClose, TokenWhitespace}, ""},
+		{"a:b", []TokenKind{TokenKey, TokenString}, ""},
+		{"a\\x b", []TokenKind{TokenString, TokenWhitespace, TokenString}, "1:2: invalid escape code \\x"},
+		{"\"a b", []TokenKind{TokenString}, "1:4: miss-matched quotes in string"},
+	}
+	for _, test := range tests {
+		lex := NewLexer([]byte(test.src))
+		var kinds []TokenKind
+		var errs []string
+		for {
+			tok, err := lex.Next()
+			if err != nil {
+				errs = append(errs, err.Error())
+			}
+			if tok.Kind == TokenEOF {
+				break
+			}
+			kinds = append(kinds, tok.Kind)
+		}
+		if fmt.Sprint(kinds) != fmt.Sprint(test.kinds) {
+			t.Errorf("%q: kinds = %v want %v", test.src, kinds, test.kinds)
+		}
+		if err := fmt.Sprint(errs); test.err == "" && len(errs) > 0 || test.err != "" && err != fmt.Sprint([]string{test.err}) {
+			t.Errorf("%q: errors = %v want %q", test.src, errs, test.err)
+		}
+	}
+	if s := TokenKind(42).String(); s != "token kind 42" {
+		t.Errorf("TokenKind(42).String() = %q", s)
+	}
+}
@@ -11,6 +11,26 @@ type Location struct {
 	Column uint32 // Column number counting from zero.
 }
 
+// Range of text input.
+// The end location and offset refer to the position just past the range.
+type Span struct {
+	Start       Location
+	End         Location
+	StartOffset int // Byte offset of the start in the text input.
+	EndOffset   int // Byte offset of the end in the text input.
+}
+
+// Implements fmt.Stringer
+func (span Span) String() string {
+	return fmt.Sprintf("%s-%s", span.Start, span.End)
+}
+
+// Get the text input covered by the span.
+// text must be the input the span was produced from.
+func (span Span) Bytes(text []byte) []byte {
+	return text[span.StartOffset:span.EndOffset]
+}
+
 // Update location information (counting lines and columns) from a byte slice.
 func (location *Location) updateFromBytes(bytes []byte) {
 	for _, c := range bytes {
@@ -42,8 +62,14 @@ func (location Location) String() string {
 
 // Format an error with the parser location in text input.
 func (location Location) Errorf(format string, a ...interface{}) *ParseError {
+	return location.errorf(ErrOther, format, a...)
+}
+
+// Format an error of the specified kind with the parser location in text input.
+func (location Location) errorf(kind ErrorKind, format string, a ...interface{}) *ParseError {
 	return &ParseError{
 		Location: location,
+		Kind:     kind,
 		Message:  fmt.Sprintf(format, a...),
 	}
 }
@@ -0,0 +1,208 @@
+package pot
+
+// Conflicting versions of a value, or of a sequence of list items, in a merge.
+// A version is empty if the value was absent or the items were removed.
+type Conflict struct {
+	Base   []*Node // Version of the common ancestor.
+	Ours   []*Node // Our version.
+	Theirs []*Node // Their version.
+}
+
+// Merge the changes made to two versions of a document, ours and theirs,
+// since their common ancestor base.
+//
+// Values changed in only one of the versions are taken from that version.
+// Values changed in both versions are merged recursively if they are
+// dictionaries or lists in all versions and are otherwise taken from either
+// version if the changes are the same. Remaining changes are conflicts.
+//
+// Dictionary entries are matched by key and by their index among the entries
+// with the same key, like Diff does. The merged dictionary has the order of
+// our entries, with entries added by them following the entry they follow in
+// their version. List items are merged like lines of text by diff3, matching
+// unchanged items using the longest common subsequence of equal items. Root
+// level values are merged as a list.
+//
+// Conflicts are held by nodes of kind ConflictNode in place of dictionary
+// entry values or list items. Returns the merged document and the number of
+// conflicts. Documents with conflicts are formatted with conflict markers by
+// Printer, which makes them invalid POT text until the conflicts are resolved.
+func Merge(base, ours, theirs []*Node) ([]*Node, int) {
+	var m merger
+	merged := m.mergeList(NewList(base...), NewList(ours...), NewList(theirs...))
+	return merged.Items, m.conflicts
+}
+
+// State of a three-way merge.
+type merger struct {
+	conflicts int // Number of conflicts.
+}
+
+// Create a conflict node for versions of a value or list items.
+func (m *merger) conflict(base, ours, theirs []*Node) *Node {
+	m.conflicts++
+	return &Node{Kind: ConflictNode, Conflict: &Conflict{base, ours, theirs}}
+}
+
+// Merge versions of a value, any of which may be nil for an absent value.
+// Returns the merged value or nil if it's absent.
+func (m *merger) merge(base, ours, theirs *Node) *Node {
+	switch {
+	case equalNodes(ours, theirs):
+		return ours
+	case equalNodes(base, ours):
+		return theirs
+	case equalNodes(base, theirs):
+		return ours
+	case base != nil && ours != nil && theirs != nil && base.Kind == ours.Kind && base.Kind == theirs.Kind:
+		switch base.Kind {
+		case DictNode:
+			return m.mergeDict(base, ours, theirs)
+		case ListNode:
+			return m.mergeList(base, ours, theirs)
+		}
+	}
+	return m.conflict(optionalNode(base), optionalNode(ours), optionalNode(theirs))
+}
+
+// Merge versions of a dictionary.
+func (m *merger) mergeDict(base, ours, theirs *Node) *Node {
+	// Identity of an entry in a dictionary.
+	type occurrence struct {
+		key   string
+		index int
+	}
+	occurrences := func(node *Node) ([]occurrence, map[occurrence]*Node) {
+		counts := make(map[string]int)
+		ids := make([]occurrence, len(node.Entries))
+		values := make(map[occurrence]*Node, len(node.Entries))
+		for i, entry := range node.Entries {
+			ids[i] = occurrence{entry.Key, counts[entry.Key]}
+			values[ids[i]] = entry.Value
+			counts[entry.Key]++
+		}
+		return ids, values
+	}
+	_, baseValues := occurrences(base)
+	ourIDs, ourValues := occurrences(ours)
+	theirIDs, theirValues := occurrences(theirs)
+
+	merged := &Node{Kind: DictNode, Location: ours.Location}
+	var mergedIDs []occurrence
+	for i, id := range ourIDs {
+		if value := m.merge(baseValues[id], ourValues[id], theirValues[id]); value != nil {
+			merged.Entries = append(merged.Entries, Entry{id.key, value, ours.Entries[i].Location})
+			mergedIDs = append(mergedIDs, id)
+		}
+	}
+
+	pos := 0 // Index in merged entries following their previous entry.
+	for i, id := range theirIDs {
+		if _, ok := ourValues[id]; ok {
+			for j, mergedID := range mergedIDs {
+				if mergedID == id {
+					pos = j + 1
+				}
+			}
+			continue
+		}
+		if value := m.merge(baseValues[id], nil, theirValues[id]); value != nil {
+			entry := Entry{id.key, value, theirs.Entries[i].Location}
+			merged.Entries = append(merged.Entries[:pos], append([]Entry{entry}, merged.Entries[pos:]...)...)
+			mergedIDs = append(mergedIDs[:pos], append([]occurrence{id}, mergedIDs[pos:]...)...)
+			pos++
+		}
+	}
+	return merged
+}
+
+// Merge versions of a list.
+func (m *merger) mergeList(base, ours, theirs *Node) *Node {
+	// Map base item indices to the indices of the equal items in each version.
+	matched := func(matches [][2]int) map[int]int {
+		indices := make(map[int]int, len(matches))
+		for _, match := range matches {
+			indices[match[0]] = match[1]
+		}
+		return indices
+	}
+	ourMatches := matched(matchItems(base.Items, ours.Items))
+	theirMatches := matched(matchItems(base.Items, theirs.Items))
+
+	merged := &Node{Kind: ListNode, Location: ours.Location}
+	i, j, k := 0, 0, 0 // Indices of the next base, our and their items.
+	for i < len(base.Items) || j < len(ours.Items) || k < len(theirs.Items) {
+		// Find the next base item left unchanged in both versions.
+		next, nextOurs, nextTheirs := i, len(ours.Items), len(theirs.Items)
+		for ; next < len(base.Items); next++ {
+			o, oursOK := ourMatches[next]
+			t, theirsOK := theirMatches[next]
+			if oursOK && theirsOK {
+				nextOurs, nextTheirs = o, t
+				break
+			}
+		}
+
+		// Merge the items changed before the unchanged item.
+		b, o, t := base.Items[i:next], ours.Items[j:nextOurs], theirs.Items[k:nextTheirs]
+		switch {
+		case equalItems(b, o):
+			merged.Items = append(merged.Items, t...)
+		case equalItems(b, t) || equalItems(o, t):
+			merged.Items = append(merged.Items, o...)
+		case len(b) == len(o) && len(b) == len(t):
+			for n := range b {
+				merged.Items = append(merged.Items, m.merge(b[n], o[n], t[n]))
+			}
+		default:
+			merged.Items = append(merged.Items, m.conflict(b, o, t))
+		}
+
+		if next < len(base.Items) {
+			merged.Items = append(merged.Items, ours.Items[nextOurs])
+			next, nextOurs, nextTheirs = next+1, nextOurs+1, nextTheirs+1
+		}
+		i, j, k = next, nextOurs, nextTheirs
+	}
+	return merged
+}
+
+// Get a slice holding node or an empty slice if node is nil.
+func optionalNode(node *Node) []*Node {
+	if node == nil {
+		return nil
+	}
+	return []*Node{node}
+}
+
+// Check if two values are equal, ignoring their locations. Nil values are only
+// equal to nil values.
+func equalNodes(a, b *Node) bool {
+	switch {
+	case a == nil || b == nil:
+		return a == b
+	case a.Kind != b.Kind || a.Value != b.Value || len(a.Entries) != len(b.Entries) || len(a.Items) != len(b.Items):
+		return false
+	case a.Kind == ConflictNode:
+		return a == b
+	}
+	for i, entry := range a.Entries {
+		if entry.Key != b.Entries[i].Key || !equalNodes(entry.Value, b.Entries[i].Value) {
+			return false
+		}
+	}
+	return equalItems(a.Items, b.Items)
+}
+
+// Check if two sequences of values are equal.
+func equalItems(a, b []*Node) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !equalNodes(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
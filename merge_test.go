@@ -0,0 +1,84 @@
+package pot
+
+import (
+	"fmt"
+	"testing"
+)
+
+func ExampleMerge() {
+	parse := func(s string) []*Node {
+		nodes, _ := Parse([]byte(s))
+		return nodes
+	}
+	base := parse(`{ name: frontend replicas: 2 ports: [ 80 443 ] }`)
+	ours := parse(`{ name: frontend replicas: 3 ports: [ 80 443 8080 ] }`)
+	theirs := parse(`{ name: web replicas: 4 ports: [ 80 443 ] debug: true }`)
+
+	merged, conflicts := Merge(base, ours, theirs)
+	buf, _ := FormatNodes(merged)
+	fmt.Printf("%s\n%d conflicts\n", buf, conflicts)
+	// Output:
+	// {
+	//     name: web
+	// <<<<<<< ours
+	//     replicas: 3
+	// ||||||| base
+	//     replicas: 2
+	// =======
+	//     replicas: 4
+	// >>>>>>> theirs
+	//     ports: [ 80 443 8080 ]
+	//     debug: true
+	// }
+	// 1 conflicts
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		base, ours, theirs string
+		merged             string
+		conflicts          int
+	}{
+		{"a", "a", "a", "a", 0},
+		{"a", "b", "a", "b", 0},
+		{"a", "a", "b", "b", 0},
+		{"a", "b", "b", "b", 0},
+		{"a", "b", "c", "<<<<<<< ours\nb\n||||||| base\na\n=======\nc\n>>>>>>> theirs", 1},
+		{"a b c", "b c", "a b", "b", 0},
+		{"", "a", "b", "<<<<<<< ours\na\n||||||| base\n=======\nb\n>>>>>>> theirs", 1},
+
+		// Dictionaries.
+		{"{ a: b c: d }", "{ c: d a: b x: y }", "{ a: b c: e }", "{ c: e a: b x: y }", 0},
+		{"{ a: b c: d }", "{ a: b }", "{ a: b c: d }", "{ a: b }", 0},
+		{"{ a: b c: d }", "{ a: b c: d }", "{ x: y a: b z: w }", "{ x: y a: b z: w }", 0},
+		{"{ a: 1 a: 2 }", "{ a: 1 a: 3 }", "{ a: 0 a: 2 a: 4 }", "{ a: 0 a: 3 a: 4 }", 0},
+		{"{ a: { b: c } }", "{ a: { b: d } }", "{ a: { b: c e: f } }", "{ a: { b: d e: f } }", 0},
+		{"{ a: b }", "{ a: c }", "{ }", "{\n<<<<<<< ours\n    a: c\n||||||| base\n    a: b\n=======\n>>>>>>> theirs\n}", 1},
+
+		// Lists.
+		{"[ a b c ]", "[ x a b c ]", "[ a b c y ]", "[ x a b c y ]", 0},
+		{"[ a b c ]", "[ a c ]", "[ a b c d ]", "[ a c d ]", 0},
+		{"[ a b c ]", "[ a x c ]", "[ a x c ]", "[ a x c ]", 0},
+		{"[ a { b: c } ]", "[ a { b: d } ]", "[ a { b: c e: f } ]", "[ a { b: d e: f } ]", 0},
+		{"[ a b c ]", "[ a x y c ]", "[ a z c ]", "[\n    a\n<<<<<<< ours\n    x\n    y\n||||||| base\n    b\n=======\n    z\n>>>>>>> theirs\n    c\n]", 1},
+	}
+	printer := Printer{Compact: true}
+	for _, test := range tests {
+		var versions [3][]*Node
+		for i, s := range []string{test.base, test.ours, test.theirs} {
+			nodes, err := Parse([]byte(s))
+			if err != nil {
+				t.Fatal(err)
+			}
+			versions[i] = nodes
+		}
+		merged, conflicts := Merge(versions[0], versions[1], versions[2])
+		buf, err := printer.PrintNodes(merged)
+		if err != nil {
+			t.Errorf("Merge(%q, %q, %q) formatting error: %s", test.base, test.ours, test.theirs, err)
+		} else if string(buf) != test.merged || conflicts != test.conflicts {
+			t.Errorf("Merge(%q, %q, %q) = %q, %d want %q, %d",
+				test.base, test.ours, test.theirs, buf, conflicts, test.merged, test.conflicts)
+		}
+	}
+}
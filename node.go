@@ -0,0 +1,301 @@
+package pot
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Kind of document node.
+type NodeKind int
+
+const (
+	DictNode     NodeKind = iota // Dictionary with ordered entries.
+	ListNode                     // List of nodes.
+	StringNode                   // String value.
+	ConflictNode                 // Merge conflict, see Merge.
+)
+
+// Implements fmt.Stringer.
+func (kind NodeKind) String() string {
+	switch kind {
+	case DictNode:
+		return "dictionary"
+	case ListNode:
+		return "list"
+	case StringNode:
+		return "string"
+	case ConflictNode:
+		return "conflict"
+	}
+	return "unknown"
+}
+
+// Dictionary entry of a document node.
+type Entry struct {
+	Key      string
+	Value    *Node
+	Location Location // Key location in the text input.
+}
+
+// Document node.
+//
+// Nodes form an in-memory document model that can be looked up repeatedly,
+// modified and serialized back into POT text. Unlike the parsers they are
+// not limited to a single forward pass. Dictionaries keep their entries in
+// order and may hold duplicate keys.
+//
+// Node implements Unmarshaler and Marshaler so it may be used to capture parts
+// of a document that are unmarshaled into Go structs.
+type Node struct {
+	Kind     NodeKind
+	Value    string    // Value of string nodes.
+	Entries  []Entry   // Entries of dictionary nodes.
+	Items    []*Node   // Items of list nodes.
+	Conflict *Conflict // Versions of conflict nodes.
+	Location Location  // Location in the text input, zero for nodes created by the application.
+}
+
+// Parse POT text into document nodes, one for each root level value.
+// Returns the nodes or the first error.
+func Parse(pot []byte) ([]*Node, error) {
+	return parseNodes(NewParser(pot))
+}
+
+// Parse the values returned by parser into document nodes.
+// Returns the nodes or the first error.
+func parseNodes(parser Parser) ([]*Node, error) {
+	var nodes []*Node
+	scanner := NewParserScanner(parser)
+	for scanner.Scan() {
+		node := new(Node)
+		scanner.InjectError(node.UnmarshalPOT(scanner.SubParser()))
+		nodes = append(nodes, node)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// Parse POT text into document nodes like Parse does but recover from errors
+// by skipping to the next key in a dictionary or the next value in a list or
+// at the root level. Returns the nodes that could be parsed and an ErrorList
+// holding every error or nil if there were none.
+func ParseRecover(pot []byte) ([]*Node, error) {
+	var nodes []*Node
+	scanner := NewRecoveringParserScanner(NewParser(pot))
+	for scanner.Scan() {
+		node := new(Node)
+		node.unmarshal(scanner.SubParser(), scanner.es)
+		nodes = append(nodes, node)
+	}
+	return nodes, scanner.Err()
+}
+
+// Format document nodes as POT text using the PrettyPrint layout, one root
+// level value for each node.
+func FormatNodes(nodes []*Node) ([]byte, error) {
+	return prettyPrinter.PrintNodes(nodes)
+}
+
+// Create a new empty dictionary node.
+func NewDict() *Node {
+	return &Node{Kind: DictNode}
+}
+
+// Create a new list node holding items.
+func NewList(items ...*Node) *Node {
+	return &Node{Kind: ListNode, Items: items}
+}
+
+// Create a new string node.
+func NewString(value string) *Node {
+	return &Node{Kind: StringNode, Value: value}
+}
+
+// Get the value of the first entry with the specified key of a dictionary node
+// or nil if there is no such entry.
+func (node *Node) Lookup(key string) *Node {
+	for _, entry := range node.Entries {
+		if entry.Key == key {
+			return entry.Value
+		}
+	}
+	return nil
+}
+
+// Get the values of all entries with the specified key of a dictionary node in
+// order.
+func (node *Node) LookupAll(key string) []*Node {
+	var values []*Node
+	for _, entry := range node.Entries {
+		if entry.Key == key {
+			values = append(values, entry.Value)
+		}
+	}
+	return values
+}
+
+// Append an entry to a dictionary node.
+func (node *Node) Append(key string, value *Node) {
+	node.Entries = append(node.Entries, Entry{Key: key, Value: value})
+}
+
+// Insert an entry at index i of a dictionary node.
+func (node *Node) Insert(i int, key string, value *Node) {
+	node.Entries = append(node.Entries, Entry{})
+	copy(node.Entries[i+1:], node.Entries[i:])
+	node.Entries[i] = Entry{Key: key, Value: value}
+}
+
+// Append an item to a list node.
+func (node *Node) AppendItem(item *Node) {
+	node.Items = append(node.Items, item)
+}
+
+// Insert an item at index i of a list node.
+func (node *Node) InsertItem(i int, item *Node) {
+	node.Items = append(node.Items, nil)
+	copy(node.Items[i+1:], node.Items[i:])
+	node.Items[i] = item
+}
+
+// Remove the entry or item at index i of a dictionary or list node.
+func (node *Node) Remove(i int) {
+	switch node.Kind {
+	case DictNode:
+		node.Entries = append(node.Entries[:i], node.Entries[i+1:]...)
+	case ListNode:
+		node.Items = append(node.Items[:i], node.Items[i+1:]...)
+	}
+}
+
+// Remove all entries with the specified key from a dictionary node.
+// Returns the number of removed entries.
+func (node *Node) RemoveKey(key string) int {
+	entries := node.Entries[:0]
+	for _, entry := range node.Entries {
+		if entry.Key != key {
+			entries = append(entries, entry)
+		}
+	}
+	n := len(node.Entries) - len(entries)
+	node.Entries = entries
+	return n
+}
+
+// Implements Unmarshaler.
+// Replaces the node with the value produced by parser.
+func (node *Node) UnmarshalPOT(parser Parser) error {
+	es := new(errorSink)
+	node.unmarshal(parser, es)
+	return es.err()
+}
+
+// Replace the node with the value produced by parser.
+// Errors are sent to the specified error sink. Dictionaries and lists being
+// unmarshaled are kept on a stack instead of recursing.
+func (node *Node) unmarshal(parser Parser, es *errorSink) {
+	type block struct {
+		node    *Node
+		scanner *ParserScanner
+		key     *DictKey // Key of the next dictionary value.
+	}
+	var blocks []block
+	for parser != nil {
+		*node = Node{Location: parser.Location()}
+		switch parser := parser.(type) {
+		case *Dict:
+			node.Kind = DictNode
+			blocks = append(blocks, block{node: node, scanner: newParserScannerErrorSink(parser, es)})
+		case *List:
+			node.Kind = ListNode
+			blocks = append(blocks, block{node: node, scanner: newParserScannerErrorSink(parser, es)})
+		case *String:
+			node.Kind = StringNode
+			node.Value = string(parser.Bytes())
+		default:
+			es.send(parser.Location().errorf(ErrUnmarshal, "cannot unmarshal %s into document node", parser.Name()))
+		}
+
+		// Find the next value of the innermost open dictionary or list.
+		parser = nil
+		for parser == nil && len(blocks) > 0 {
+			b := &blocks[len(blocks)-1]
+			if !b.scanner.Scan() {
+				blocks = blocks[:len(blocks)-1]
+				continue
+			}
+			switch subparser := b.scanner.SubParser().(type) {
+			case *DictKey:
+				b.key = subparser
+			default:
+				node, parser = new(Node), subparser
+				if b.node.Kind == DictNode {
+					b.node.Entries = append(b.node.Entries, Entry{string(b.key.Bytes()), node, b.key.Location()})
+				} else {
+					b.node.Items = append(b.node.Items, node)
+				}
+			}
+		}
+	}
+}
+
+// Implements Marshaler.
+// The node is formatted on a single line.
+func (node *Node) MarshalPOT() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := node.write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Implements fmt.Stringer.
+// The node is formatted on a single line, errors are included in the output.
+func (node *Node) String() string {
+	buf, err := node.MarshalPOT()
+	if err != nil {
+		return fmt.Sprintf("%%!(%s)", err)
+	}
+	return string(buf)
+}
+
+// Write the node as POT text on a single line to buf.
+func (node *Node) write(buf *bytes.Buffer) error {
+	if node == nil {
+		return fmt.Errorf("nil document node")
+	}
+	switch node.Kind {
+	case DictNode:
+		buf.WriteString("{ ")
+		for _, entry := range node.Entries {
+			if !validKey([]byte(entry.Key), true) {
+				return fmt.Errorf("invalid dictionary key %q", entry.Key)
+			}
+			buf.WriteString(entry.Key)
+			buf.WriteString(": ")
+			if err := entry.Value.write(buf); err != nil {
+				return err
+			}
+			buf.WriteByte(' ')
+		}
+		buf.WriteByte('}')
+	case ListNode:
+		buf.WriteString("[ ")
+		for _, item := range node.Items {
+			if err := item.write(buf); err != nil {
+				return err
+			}
+			buf.WriteByte(' ')
+		}
+		buf.WriteByte(']')
+	case StringNode:
+		buf.WriteString(formatString([]byte(node.Value)))
+	case ConflictNode:
+		return fmt.Errorf("merge conflict can not be formatted on a single line")
+	default:
+		return fmt.Errorf("invalid node kind %d", node.Kind)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package pot
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func ExampleParse() {
+	nodes, err := Parse([]byte("{ name: frontend port: 80 route: /a route: /b }"))
+	if err != nil {
+		fmt.Printf("error: %s\n", err)
+		return
+	}
+	dict := nodes[0]
+	fmt.Println(dict.Lookup("name").Value, dict.Lookup("port").Location, len(dict.LookupAll("route")))
+
+	dict.Lookup("port").Value = "8080"
+	dict.RemoveKey("route")
+	dict.Insert(1, "hosts", NewList(NewString("a.example.com"), NewString("b example")))
+	dict.Append("tls", NewDict())
+	dict.Lookup("tls").Append("enabled", NewString("true"))
+
+	buf, err := FormatNodes(nodes)
+	if err != nil {
+		fmt.Printf("error: %s\n", err)
+		return
+	}
+	fmt.Printf("%s\n", buf)
+	// Output:
+	// frontend 1:23 2
+	// {
+	//     name:  frontend
+	//     hosts: [ a.example.com "b example" ]
+	//     port:  8080
+	//     tls: {
+	//         enabled: true
+	//     }
+	// }
+}
+
+func TestNode_Edit(t *testing.T) {
+	list := NewList(NewString("a"), NewString("c"))
+	list.InsertItem(1, NewString("b"))
+	list.AppendItem(NewString("d"))
+	list.Remove(0)
+	if s := list.String(); s != "[ b c d ]" {
+		t.Errorf("String() = %s want [ b c d ]", s)
+	}
+
+	dict := NewDict()
+	dict.Append("a", NewString("1"))
+	dict.Append("b", NewString("2"))
+	dict.Append("a", NewString("3"))
+	dict.Remove(1)
+	if s := dict.String(); s != "{ a: 1 a: 3 }" {
+		t.Errorf("String() = %s want { a: 1 a: 3 }", s)
+	}
+	if n := dict.RemoveKey("a"); n != 2 || len(dict.Entries) != 0 {
+		t.Errorf("RemoveKey() = %d, %d entries left", n, len(dict.Entries))
+	}
+
+	dict.Append("-a", NewString("1"))
+	if _, err := FormatNodes([]*Node{dict}); err == nil {
+		t.Errorf("FormatNodes() with invalid key succeeded")
+	}
+	dict.Entries[0].Key = "server_name"
+	if buf, err := FormatNodes([]*Node{dict}); err != nil || string(buf) != "{\n    server_name: 1\n}" {
+		t.Errorf("FormatNodes() with extended key = (%q, %v)", buf, err)
+	}
+	dict.Entries[0] = Entry{Key: "a"}
+	if _, err := dict.MarshalPOT(); err == nil {
+		t.Errorf("MarshalPOT() with nil value succeeded")
+	}
+}
+
+// Test that nodes capture parts of a document when unmarshaling.
+func TestNode_Unmarshal(t *testing.T) {
+	var v struct {
+		Name  string
+		Extra *Node
+		List  Node
+	}
+	if err := Unmarshal([]byte("{ name: a extra: { b: [ c ] } list: [ d ] }"), &v); err != nil {
+		t.Fatal(err)
+	}
+	want := &Node{Kind: DictNode, Location: Location{0, 17}, Entries: []Entry{
+		{"b", &Node{Kind: ListNode, Location: Location{0, 22}, Items: []*Node{
+			{Kind: StringNode, Value: "c", Location: Location{0, 24}},
+		}}, Location{0, 19}},
+	}}
+	if !reflect.DeepEqual(v.Extra, want) {
+		t.Errorf("Extra = %#v want %#v", v.Extra, want)
+	}
+	if v.List.String() != "[ d ]" {
+		t.Errorf("List = %s want [ d ]", &v.List)
+	}
+
+	buf, err := Marshal(&v)
+	if want := "{ Name: a Extra: { b: [ c ] } List: [ d ] }"; err != nil || string(buf) != want {
+		t.Errorf("Marshal() = (%s, %v) want %s", buf, err, want)
+	}
+
+	if _, err := Parse([]byte("{ a: [ b }")); err == nil {
+		t.Errorf("Parse() with invalid input succeeded")
+	}
+}
+
+// Test that nesting is limited by default and that nodes are unmarshaled
+// without recursing when it isn't.
+func TestNode_UnmarshalNesting(t *testing.T) {
+	nested := func(n int) []byte {
+		return []byte(strings.Repeat("{ a: [ ", n) + strings.Repeat("] }", n))
+	}
+	if _, err := Parse(nested(DefaultMaxDepth)); !errors.Is(err, ErrDepthLimit) {
+		t.Errorf("Parse() error = %v want %v", err, ErrDepthLimit)
+	}
+
+	options := ParserOptions{MaxDepth: -1}
+	value, err := NewParserWithOptions(nested(100000), &options).Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var node Node
+	if err = node.UnmarshalPOT(value); err != nil {
+		t.Fatal(err)
+	}
+	depth := 1
+	for n := node.Entries[0].Value; len(n.Items) > 0; n = n.Items[0].Entries[0].Value {
+		depth++
+	}
+	if depth != 100000 {
+		t.Errorf("depth = %d want 100000", depth)
+	}
+}
+
+var exampleParseRecover = `{ a: b
+  -bad: c
+  d: e:f
+  g: ]
+  h: "ok" }
+[ x } y ]
+{ unterminated: [ z
+`
+
+func ExampleParseRecover() {
+	nodes, err := ParseRecover([]byte(exampleParseRecover))
+	for _, node := range nodes {
+		fmt.Println(node)
+	}
+	var list ErrorList
+	if errors.As(err, &list) {
+		for _, err := range list {
+			fmt.Println(err)
+		}
+	}
+	// Output:
+	// { a: b h: ok }
+	// [ x y ]
+	// 2:2: invalid character '-' in key
+	// 3:6: invalid character ':' in string
+	// 4:5: invalid character ']' in string
+	// 6:4: invalid character '}' in string
+	// 8:0: end of input while parsing '{}' block
+}
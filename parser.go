@@ -1,5 +1,11 @@
 package pot
 
+import (
+	"iter"
+	"unicode"
+	"unicode/utf8"
+)
+
 // Parser interface implemented by Dict, DictKey, List and String parsers.
 type Parser interface {
 	// Parser name.
@@ -16,17 +22,44 @@ type Parser interface {
 	Location() Location
 }
 
+// Optional interface of parsers knowing the span of their text. Implemented by
+// all parsers of this package.
+type Spanner interface {
+	// Get the span of the text the parser was initialized with in the original
+	// text input. Byte offsets are reset like locations are.
+	Span() Span
+}
+
 // Root level parser capable of parsing multiple root level objects from the
 // same text input.
 type Root struct {
-	org parserBuf  // Text the parser was initialized with.
-	buf *parserBuf // Text buffer the parser operates on.
+	org parserBuf // Text the parser was initialized with.
+	blockPos
+	err     error // Error returned by the next call to Next.
+	iterErr error // Error that stopped iteration with All.
 }
 
 // Create a new root level parser parsing the supplied text.
 func NewParser(pot []byte) Parser {
+	return NewParserWithOptions(pot, nil)
+}
+
+// Create a new root level parser parsing the supplied text using options.
+// Default options are used if options is nil.
+func NewParserWithOptions(pot []byte, options *ParserOptions) Parser {
 	buf := newParserBuf(pot)
-	return &Root{*buf, buf}
+	buf.options = options
+	return newRoot(buf)
+}
+
+// Create a new root level parser parsing the supplied parser buffer.
+func newRoot(buf *parserBuf) *Root {
+	root := &Root{org: *buf}
+	if root.err = buf.checkInputSize(); root.err != nil {
+		buf = &parserBuf{location: buf.location, offset: buf.offset, options: buf.options}
+	}
+	root.blockPos = blockPos{streamPos: streamPos{stream: newTokenStream(buf), end: -1}, open: TokenEOF}
+	return root
 }
 
 func (root *Root) Name() string {
@@ -36,7 +69,28 @@ func (root *Root) Name() string {
 // Get the next parser or nil on end of input or an error.
 // The returned parser may be a Dict, List or String.
 func (root *Root) Next() (Parser, error) {
-	return scanValue(root.buf)
+	if err := root.err; err != nil {
+		root.err = nil
+		return nil, err
+	}
+	return root.nextParser()
+}
+
+// Iterate over the remaining root level values.
+// The values may be Dict, List or String parsers. Iteration stops at the end of
+// input or at the first error, which is then returned by Err.
+func (root *Root) All() iter.Seq[Parser] {
+	return allValues(root, &root.iterErr)
+}
+
+// Returns the error that stopped iteration with All or nil.
+func (root *Root) Err() error {
+	return root.iterErr
+}
+
+// Resynchronise after a parse error by skipping the offending value.
+func (root *Root) resync() {
+	root.skip()
 }
 
 // Get text the parser was initialized with.
@@ -49,25 +103,38 @@ func (root *Root) Location() Location {
 	return root.org.location
 }
 
+// Get the span of the parser text in the original text input.
+func (root *Root) Span() Span {
+	return root.org.span()
+}
+
+// Iterate over the values returned by parser until nil or an error is
+// returned. The error is stored in err.
+func allValues(parser Parser, err *error) iter.Seq[Parser] {
+	return func(yield func(Parser) bool) {
+		for {
+			var value Parser
+			if value, *err = parser.Next(); value == nil {
+				return
+			}
+			if !yield(value) {
+				return
+			}
+		}
+	}
+}
+
 // Dictionary parser.
 type Dict struct {
-	org   parserBuf  // Text the parser was initialized with.
-	buf   *parserBuf // Text buffer the parser operates on.
-	count int        // Number of returned parsers.
+	org parserBuf // Text the parser was initialized with.
+	blockPos
+	iterErr error // Error that stopped iteration with All.
 }
 
 // Create a new dictionary parser parsing the supplied text.
 func NewDictParser(pot []byte) *Dict {
-	return newDictParser(newParserBuf(pot))
-}
-
-// Create a new dictionary parser parsing the supplied parser buffer.
-func newDictParser(buf *parserBuf) *Dict {
-	dict := &Dict{*buf, buf, 0}
-	buf.stripBlock('{', '}')
-	// Trim space to make IsEmpty() work out of the gate.
-	buf.trimSpaceLeft()
-	return dict
+	buf := newParserBuf(pot)
+	return &Dict{org: *buf, blockPos: newBlockPos(newTokenStream(buf), TokenBraceOpen)}
 }
 
 func (dict *Dict) Name() string {
@@ -77,24 +144,47 @@ func (dict *Dict) Name() string {
 // Get the next parser or nil on end of input or an error.
 // Every even call returns a key which is of type DictKey.
 // Every odd call returns a value which may be a Dict, List or String.
-func (dict *Dict) Next() (parser Parser, err error) {
-	if dict.count%2 == 0 {
-		parser, err = scanKey(dict.buf)
-	} else {
-		parser, err = scanValue(dict.buf)
-		if parser == nil && err == nil {
-			err = dict.buf.errorf("key without value in dictionary")
+func (dict *Dict) Next() (Parser, error) {
+	return dict.nextParser()
+}
+
+// Iterate over the remaining dictionary entries.
+// The values may be Dict, List or String parsers. Iteration stops at the end of
+// the dictionary or at the first error, which is then returned by Err.
+func (dict *Dict) All() iter.Seq2[*DictKey, Parser] {
+	return func(yield func(*DictKey, Parser) bool) {
+		for {
+			key, err := dict.Next()
+			if key == nil {
+				dict.iterErr = err
+				return
+			}
+			value, err := dict.Next()
+			if value == nil {
+				dict.iterErr = err
+				return
+			}
+			if !yield(key.(*DictKey), value) {
+				return
+			}
 		}
 	}
-	if parser != nil {
-		dict.count++
-	}
-	return
+}
+
+// Returns the error that stopped iteration with All or nil.
+func (dict *Dict) Err() error {
+	return dict.iterErr
+}
+
+// Resynchronise after a parse error by skipping to the next key.
+func (dict *Dict) resync() {
+	dict.skipToKey()
+	dict.count += dict.count % 2
 }
 
 // Check if the parser has consumed all data.
 func (dict *Dict) IsEmpty() bool {
-	return len(dict.buf.bytes) == 0
+	return dict.atEnd()
 }
 
 // Get text the parser was initialized with.
@@ -107,6 +197,11 @@ func (dict *Dict) Location() Location {
 	return dict.org.location
 }
 
+// Get the span of the parser text in the original text input.
+func (dict *Dict) Span() Span {
+	return dict.org.span()
+}
+
 // Dictionary key type.
 // A unique "string" type is used to be able to separate keys from string values
 // in type switches.
@@ -136,22 +231,22 @@ func (key *DictKey) Location() Location {
 	return (*String)(key).Location()
 }
 
+// Get the span of the parser text in the original text input.
+func (key *DictKey) Span() Span {
+	return (*String)(key).Span()
+}
+
 // List parser.
 type List struct {
-	org parserBuf  // Text the parser was initialized with.
-	buf *parserBuf // Text buffer the parser operates on.
+	org parserBuf // Text the parser was initialized with.
+	blockPos
+	iterErr error // Error that stopped iteration with All.
 }
 
 // Create a new list parser parsing the supplied text.
 func NewListParser(pot []byte) *List {
-	return newListParser(newParserBuf(pot))
-}
-
-// Create a new list parser parsing the supplied parser buffer.
-func newListParser(buf *parserBuf) *List {
-	list := &List{*buf, buf}
-	buf.stripBlock('[', ']')
-	return list
+	buf := newParserBuf(pot)
+	return &List{org: *buf, blockPos: newBlockPos(newTokenStream(buf), TokenBracketOpen)}
 }
 
 func (list *List) Name() string {
@@ -161,7 +256,24 @@ func (list *List) Name() string {
 // Get the next parser or nil on end of input or an error.
 // The returned parser may be a Dict, List or String.
 func (list *List) Next() (Parser, error) {
-	return scanValue(list.buf)
+	return list.nextParser()
+}
+
+// Iterate over the remaining list values.
+// The values may be Dict, List or String parsers. Iteration stops at the end of
+// the list or at the first error, which is then returned by Err.
+func (list *List) All() iter.Seq[Parser] {
+	return allValues(list, &list.iterErr)
+}
+
+// Returns the error that stopped iteration with All or nil.
+func (list *List) Err() error {
+	return list.iterErr
+}
+
+// Resynchronise after a parse error by skipping the offending value.
+func (list *List) resync() {
+	list.skip()
 }
 
 // Get text the parser was initialized with.
@@ -174,6 +286,11 @@ func (list *List) Location() Location {
 	return list.org.location
 }
 
+// Get the span of the parser text in the original text input.
+func (list *List) Span() Span {
+	return list.org.span()
+}
+
 // String parser.
 type String parserBuf
 
@@ -196,18 +313,26 @@ var charToEscapeCode = map[byte]byte{
 
 // Format as a POT string value.
 func (str *String) String() string {
+	return formatString(str.bytes)
+}
+
+// Format bytes as a POT string value, quoting and escaping as needed.
+func formatString(bytes []byte) string {
 	quote := false
 	newBuf := false
 
-	t := str.bytes
-	for i, c := range str.bytes {
+	t := bytes
+	for i, c := range bytes {
 		switch c {
-		case '{', '}', '[', ']', ':', ' ':
+		case '{', '}', '[', ']', ':', ' ', '#':
 			quote = true
+			if newBuf {
+				t = append(t, c)
+			}
 		case '\n', '\r', '\t', '\\', '"':
 			if !newBuf {
-				t = make([]byte, 0, len(str.bytes))
-				t = append(t, str.bytes[:i]...)
+				t = make([]byte, 0, len(bytes))
+				t = append(t, bytes[:i]...)
 				newBuf = true
 			}
 			t = append(t, '\\', charToEscapeCode[c])
@@ -233,132 +358,9 @@ func (str *String) Location() Location {
 	return str.location
 }
 
-// Scans a DictKey from a text buffer.
-// Returns a parser or nil when there is no more input or an error.
-func scanKey(buf *parserBuf) (Parser, error) {
-	buf.trimSpaceLeft()
-	if len(buf.bytes) == 0 {
-		return nil, nil
-	}
-	for i, c := range buf.bytes {
-		switch {
-		case validKeyChar(i, c):
-		case c == ':' && i > 0:
-			t := buf.split(i)
-			buf.trimBytesLeft(1) // Eat ':'
-			return (*DictKey)(t), nil
-		default:
-			buf.trimBytesLeft(i)
-			return nil, buf.errorf("invalid character '%c' in key", c)
-		}
-	}
-	buf.trimAll()
-	return nil, buf.errorf("end of input while parsing key")
-}
-
-// Scans a Dict, List or String from a text buffer.
-// Returns a parser or nil when there is no more input or an error.
-func scanValue(buf *parserBuf) (parser Parser, err error) {
-	buf.trimSpaceLeft()
-	if len(buf.bytes) > 0 {
-		switch buf.bytes[0] {
-		case '{':
-			if buf, err = scanBlock(buf, '{', '}'); err == nil {
-				parser = newDictParser(buf)
-			}
-		case '[':
-			if buf, err = scanBlock(buf, '[', ']'); err == nil {
-				parser = newListParser(buf)
-			}
-		default:
-			parser, err = scanString(buf)
-		}
-	}
-	return
-}
-
-// Scans a Dict or List block from a text buffer.
-// Returns a new text buffer containing the block or an error.
-func scanBlock(buf *parserBuf, begChar, endChar byte) (*parserBuf, error) {
-	quoted := false
-	escaped := false
-	scope := 0
-	for i, c := range buf.bytes {
-		switch c {
-		case '\\':
-			escaped = !escaped
-		case '"':
-			if !escaped {
-				quoted = !quoted
-			}
-			escaped = false
-		case begChar:
-			if !quoted && !escaped {
-				scope++
-			}
-			escaped = false
-		case endChar:
-			if !quoted && !escaped {
-				scope--
-				if scope == 0 {
-					return buf.split(i + 1), nil
-				}
-			}
-			escaped = false
-		default:
-			escaped = false
-		}
-	}
-	buf.trimAll()
-	return nil, buf.errorf("end of input while parsing '%c%c' block", begChar, endChar)
-}
-
-// Scans a String from a text buffer.
-// Returns a parser or an error.
-func scanString(buf *parserBuf) (Parser, error) {
-	quoted := false
-	escaped := false
-	eval := false
-
-	var i int
-	var c byte
-loop:
-	for i, c = range buf.bytes {
-		switch c {
-		case '\\':
-			escaped = !escaped
-			eval = true
-		case '"':
-			if !escaped {
-				quoted = !quoted
-			}
-			escaped = false
-			eval = true
-		case '{', '}', '[', ']', ' ', '\n', '\r', '\t':
-			if !quoted && !escaped {
-				if i == 0 {
-					buf.trimBytesLeft(i)
-					return nil, buf.errorf("invalid character '%c' in string", c)
-				} else {
-					i--
-					break loop
-				}
-			}
-			escaped = false
-		case ':':
-			if !quoted && !escaped {
-				buf.trimBytesLeft(i)
-				return nil, buf.errorf("invalid character '%c' in string", c)
-			}
-			escaped = false
-		}
-	}
-
-	str := buf.split(i + 1)
-	if eval {
-		return evalStringBuffer(str)
-	}
-	return (*String)(str), nil
+// Get the span of the parser text in the original text input.
+func (str *String) Span() Span {
+	return (*parserBuf)(str).span()
 }
 
 var escapeCodeToChar = map[byte]byte{
@@ -367,67 +369,27 @@ var escapeCodeToChar = map[byte]byte{
 	't': '\t',
 }
 
-// Evaluate escape codes and quotes in string.
-// Returns the modified parser buffer as a string parser or an error.
-func evalStringBuffer(buf *parserBuf) (Parser, error) {
-	quoted := false
-	escaped := false
-
-	tr := make([]byte, 0, len(buf.bytes))
-	for i, c := range buf.bytes {
-		switch c {
-		case '\\':
-			if escaped {
-				tr = append(tr, c)
-			}
-			escaped = !escaped
-		case '"':
-			if escaped {
-				tr = append(tr, c)
-			} else {
-				quoted = !quoted
-			}
-			escaped = false
-		case 'n', 'r', 't':
-			if escaped {
-				tr = append(tr, escapeCodeToChar[c])
-			} else {
-				tr = append(tr, c)
-			}
-			escaped = false
-		case '{', '}', '[', ']', ':', ' ':
-			tr = append(tr, c)
-			escaped = false
-		default:
-			if escaped {
-				buf.trimBytesLeft(i)
-				return nil, buf.errorf("invalid escape code \\%c", c)
-			} else {
-				tr = append(tr, c)
-			}
-		}
-	}
-
-	if quoted {
-		buf.trimAll()
-		return nil, buf.errorf("miss-matched quotes in string")
-	}
-	if escaped {
-		buf.trimAll()
-		return nil, buf.errorf("unterminated escape code in string")
-	}
-
-	buf.bytes = tr
-	return (*String)(buf), nil
-}
-
-// Check if 'c' is a valid key character at index 'i'.
-func validKeyChar(i int, c byte) bool {
-	if c >= 'a' && c < 'z' || c >= 'A' && c < 'Z' || c >= '0' && c < '9' {
+// Check if 'r' is a valid key character at byte index 'i'.
+// Extended keys may also contain '_' in any position, '.' in any position but
+// the first and Unicode letters and digits.
+func validKeyRune(i int, r rune, extended bool) bool {
+	switch {
+	case r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9':
 		return true
-	}
-	if i > 0 && c == '-' {
+	case r == '-':
+		return i > 0
+	case !extended:
+		return false
+	case r == '_':
 		return true
+	case r == '.':
+		return i > 0
 	}
-	return false
+	return r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// Check if 'key' is a valid dictionary key using the standard or the extended
+// key characters.
+func validKey(key []byte, extended bool) bool {
+	return len(key) > 0 && invalidKeyIndex(key, extended) < 0
 }
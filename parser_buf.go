@@ -1,77 +1,104 @@
 package pot
 
-import (
-	"bytes"
-	"unicode"
-)
-
 // Helper type that wraps the input text to parse.
 // It provides a means to report correct location information in errors.
 type parserBuf struct {
-	bytes    []byte   // Text to parse
-	location Location // Parser location in text input.
+	bytes     []byte   // Text to parse
+	location  Location // Parser location in text input.
+	offset    int      // Parser byte offset in text input.
+	end       Location // Location of the end of the text the buffer was created with.
+	endOffset int      // Byte offset of the end of the text the buffer was created with.
+	options   *ParserOptions
 }
 
+// Options controlling how text input is parsed.
+// The zero value parses the standard POT format with the default nesting depth
+// limit only.
+//
+// Limits protect against excessive resource use when parsing untrusted input.
+// A limit of zero means no limit, except for the nesting depth which is limited
+// to DefaultMaxDepth unless MaxDepth is negative. Exceeding a limit produces a
+// parse error with a distinct kind.
+type ParserOptions struct {
+	// Accept an extended set of dictionary key characters. In addition to the
+	// standard characters, '_' is allowed in any position, '.' is allowed in
+	// any position but the first and Unicode letters and digits are allowed in
+	// any position.
+	ExtendedKeys bool
+
+	MaxDepth        int // Maximum nesting depth of dictionaries and lists, see DefaultMaxDepth (ErrDepthLimit).
+	MaxStringLength int // Maximum length in bytes of keys and strings as written in the input (ErrStringLimit).
+	MaxEntries      int // Maximum number of entries in a dictionary or values in a list (ErrEntryLimit).
+	MaxInputSize    int // Maximum size in bytes of the text input (ErrInputLimit).
+}
+
+// Nesting depth limit used when ParserOptions.MaxDepth is zero. It keeps code
+// recursing through nested values, such as Unmarshal and Parse, well within the
+// stack size limit.
+const DefaultMaxDepth = 10000
+
+var defaultParserOptions ParserOptions
+
 // Create buffer from byte slice.
 func newParserBuf(bytes []byte) *parserBuf {
-	return &parserBuf{bytes: bytes}
+	buf := &parserBuf{bytes: bytes, endOffset: len(bytes)}
+	buf.end.updateFromBytes(bytes)
+	return buf
 }
 
-// Format an error with the parser location in text input.
-func (buf *parserBuf) errorf(format string, a ...interface{}) error {
-	return buf.location.Errorf(format, a...)
+// Get the span of the text the buffer was created with.
+func (buf *parserBuf) span() Span {
+	return Span{buf.location, buf.end, buf.offset, buf.endOffset}
 }
 
-// Split buffer creating a new buffer.
-func (buf *parserBuf) split(n int) *parserBuf {
-	t := *buf
-	t.bytes = t.bytes[:n]
-	buf.trimBytesLeft(n)
-	return &t
+// Get the parser options of the buffer.
+func (buf *parserBuf) opts() *ParserOptions {
+	if buf.options == nil {
+		return &defaultParserOptions
+	}
+	return buf.options
 }
 
-// Strip the outer block identified by begChar and endChar.
-func (buf *parserBuf) stripBlock(begChar, endChar byte) {
-	buf.trimSpace()
-	l := len(buf.bytes)
-	if l >= 2 && buf.bytes[0] == begChar && buf.bytes[l-1] == endChar {
-		buf.trimBytesLeft(1)
-		buf.trimBytesRight(1)
+// Get the nesting depth limit or zero if there is none.
+func (buf *parserBuf) maxDepth() int {
+	switch max := buf.opts().MaxDepth; {
+	case max == 0:
+		return DefaultMaxDepth
+	case max < 0:
+		return 0
+	default:
+		return max
 	}
 }
 
-// Trim all bytes.
-func (buf *parserBuf) trimAll() {
-	buf.trimBytesLeft(len(buf.bytes))
+// Check if the extended key character set is used.
+func (buf *parserBuf) extendedKeys() bool {
+	return buf.opts().ExtendedKeys
 }
 
-// Trim bytes from the left.
-func (buf *parserBuf) trimBytesLeft(n int) {
-	buf.location.updateFromBytes(buf.bytes[:n])
-	buf.bytes = buf.bytes[n:]
-}
-
-// Trim bytes from the right.
-func (buf *parserBuf) trimBytesRight(n int) {
-	buf.bytes = buf.bytes[:len(buf.bytes)-n]
+// Check if 'key' is a valid dictionary key according to the parser options.
+func (buf *parserBuf) validKey(key []byte) bool {
+	return validKey(key, buf.extendedKeys())
 }
 
-// Trim space from the left.
-func (buf *parserBuf) trimSpaceLeft() {
-	n := bytes.IndexFunc(buf.bytes, func(r rune) bool { return !unicode.IsSpace(r) })
-	if n == -1 {
-		n = len(buf.bytes)
+// Check that the buffer is within the input size limit.
+func (buf *parserBuf) checkInputSize() error {
+	if max := buf.opts().MaxInputSize; max > 0 && len(buf.bytes) > max {
+		location := buf.location
+		location.updateFromBytes(buf.bytes[:max])
+		return location.errorf(ErrInputLimit, "input size exceeds limit of %d bytes", max)
 	}
-	buf.trimBytesLeft(n)
+	return nil
 }
 
-// Trim space from the right.
-func (buf *parserBuf) trimSpaceRight() {
-	buf.bytes = bytes.TrimRightFunc(buf.bytes, unicode.IsSpace)
+// Format an error of the specified kind with the parser location in text input.
+func (buf *parserBuf) errorf(kind ErrorKind, format string, a ...interface{}) error {
+	return buf.location.errorf(kind, format, a...)
 }
 
-// Trim space from the left and the right.
-func (buf *parserBuf) trimSpace() {
-	buf.trimSpaceLeft()
-	buf.trimSpaceRight()
+// Trim bytes from the left.
+func (buf *parserBuf) trimBytesLeft(n int) {
+	buf.location.updateFromBytes(buf.bytes[:n])
+	buf.offset += n
+	buf.bytes = buf.bytes[n:]
 }
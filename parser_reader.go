@@ -0,0 +1,197 @@
+package pot
+
+import "io"
+
+// Minimum number of bytes to make room for before reading from the input.
+const minReadSize = 4096
+
+// Root level parser reading its text input incrementally from an io.Reader.
+//
+// Root level values are returned as soon as they are complete. Memory use is
+// bounded by the largest root level value as consumed input is released.
+// Locations count from the start of the stream just like for a Root parser.
+type ReaderParser struct {
+	r        io.Reader
+	buf      []byte    // Input read but not yet consumed.
+	location Location  // Location of buf[0] in the text input.
+	offset   int       // Byte offset of buf[0] in the text input.
+	scan     rootScan  // Scan state of the next root level value in buf.
+	eof      bool      // End of input has been reached.
+	es       errorSink // First read or parse error.
+	options  *ParserOptions
+}
+
+// State used to find the end of a root level value in partial input.
+type rootScan struct {
+	pos     int  // Next byte to scan.
+	start   int  // Start of the value or -1 if not found yet.
+	begChar byte // Block begin character or 0 if scanning a string.
+	endChar byte // Block end character.
+	scope   int
+	quoted  bool
+	escaped bool
+	comment bool
+}
+
+// Create a new root level parser reading from r.
+func NewReaderParser(r io.Reader) *ReaderParser {
+	return NewReaderParserWithOptions(r, nil)
+}
+
+// Create a new root level parser reading from r using options.
+// Default options are used if options is nil.
+func NewReaderParserWithOptions(r io.Reader, options *ParserOptions) *ReaderParser {
+	return &ReaderParser{r: r, scan: rootScan{start: -1}, options: options}
+}
+
+func (rp *ReaderParser) Name() string {
+	return "root"
+}
+
+// Get the next parser or nil on end of input or an error.
+// The returned parser may be a Dict, List or String. Read errors are returned
+// as is.
+func (rp *ReaderParser) Next() (Parser, error) {
+	if err := rp.es.err(); err != nil {
+		return nil, err
+	}
+
+	n, ok := rp.scanValueEnd()
+	for !ok && !rp.eof {
+		if err := rp.fill(); err != nil {
+			rp.es.send(err)
+			return nil, err
+		}
+		if err := rp.checkInputSize(); err != nil {
+			rp.es.send(err)
+			return nil, err
+		}
+		n, ok = rp.scanValueEnd()
+	}
+	if !ok {
+		n = len(rp.buf)
+	}
+
+	buf := &parserBuf{bytes: make([]byte, n), location: rp.location, offset: rp.offset, options: rp.options}
+	copy(buf.bytes, rp.buf)
+	rp.location.updateFromBytes(rp.buf[:n])
+	rp.offset += n
+	buf.end, buf.endOffset = rp.location, rp.offset
+	rp.buf = rp.buf[n:]
+	rp.scan = rootScan{start: -1}
+
+	value := streamPos{stream: newTokenStream(buf), end: -1}
+	parser, err := value.nextValue()
+	rp.es.send(err)
+	return parser, err
+}
+
+// Returns nil as the text input is not kept in memory.
+func (rp *ReaderParser) Bytes() []byte {
+	return nil
+}
+
+// Get parser start location in the original text input.
+func (rp *ReaderParser) Location() Location {
+	return Location{}
+}
+
+// Returns a zero span as the text input is not kept in memory.
+func (rp *ReaderParser) Span() Span {
+	return Span{}
+}
+
+// Read more input into the buffer.
+func (rp *ReaderParser) fill() error {
+	if cap(rp.buf)-len(rp.buf) < minReadSize {
+		buf := make([]byte, len(rp.buf), 2*len(rp.buf)+minReadSize)
+		copy(buf, rp.buf)
+		rp.buf = buf
+	}
+	n, err := rp.r.Read(rp.buf[len(rp.buf):cap(rp.buf)])
+	rp.buf = rp.buf[:len(rp.buf)+n]
+	if err == io.EOF {
+		rp.eof = true
+		return nil
+	}
+	return err
+}
+
+// Check that the input read so far is within the input size limit.
+func (rp *ReaderParser) checkInputSize() error {
+	max := 0
+	if rp.options != nil {
+		max = rp.options.MaxInputSize
+	}
+	if max > 0 && rp.offset+len(rp.buf) > max {
+		location := rp.location
+		location.updateFromBytes(rp.buf[:max-rp.offset])
+		return location.errorf(ErrInputLimit, "input size exceeds limit of %d bytes", max)
+	}
+	return nil
+}
+
+// Find the end of the first root level value in the buffer using the same rules
+// as the lexer. Scanning resumes where it left off the previous call. Returns
+// the number of bytes up to the end of the value and true if a complete value
+// was found.
+func (rp *ReaderParser) scanValueEnd() (int, bool) {
+	s := &rp.scan
+	for ; s.pos < len(rp.buf); s.pos++ {
+		c := rp.buf[s.pos]
+		if s.comment {
+			s.comment = c != '\n'
+			continue
+		}
+		if s.start < 0 {
+			switch c {
+			case ' ', '\t', '\n', '\r', '\v', '\f':
+				continue
+			case '#':
+				s.comment = true
+				continue
+			case '{':
+				s.begChar, s.endChar = '{', '}'
+			case '[':
+				s.begChar, s.endChar = '[', ']'
+			}
+			s.start = s.pos
+		}
+
+		switch {
+		case c == '\\':
+			s.escaped = !s.escaped
+			continue
+		case c == '"':
+			if !s.escaped {
+				s.quoted = !s.quoted
+			}
+		case s.quoted || s.escaped:
+		case s.begChar != 0:
+			if c == '#' {
+				s.comment = true
+			} else if c == s.begChar {
+				s.scope++
+			} else if c == s.endChar {
+				if s.scope--; s.scope == 0 {
+					return s.pos + 1, true
+				}
+			}
+		case c == ':' || s.pos == s.start && isStringDelimiter(c):
+			return s.pos + 1, true // Let scanValue report the error.
+		case isStringDelimiter(c):
+			return s.pos, true
+		}
+		s.escaped = false
+	}
+	return 0, false
+}
+
+// Check if 'c' ends an unquoted and unescaped string.
+func isStringDelimiter(c byte) bool {
+	switch c {
+	case '{', '}', '[', ']', ' ', '\n', '\r', '\t', '#':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package pot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// Describe all parsers and their locations.
+func testDescribeParsers(wr io.Writer, parser Parser) error {
+	scanner := NewParserScanner(parser)
+	for scanner.Scan() {
+		subparser := scanner.SubParser()
+		span := subparser.(Spanner).Span()
+		fmt.Fprintf(wr, "%s:%d-%d:%s:%s\n", span, span.StartOffset, span.EndOffset, subparser.Name(), subparser.Bytes())
+		if err := testDescribeParsers(wr, subparser); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+var exampleReaderParser = "  { a: b\n   c: [ d\\ e \"{[\" ] } f\r\n\t[ g ] \"h i\" j\\{\n{}"
+
+// Test that the reader parser produces the same result as the root parser.
+func TestReaderParser(t *testing.T) {
+	inputs := []string{
+		exampleReaderParser,
+		exampleReaderParser + " ",
+		"",
+		" \n ",
+		"a b: c",
+		"{ a: b ",
+		"a ]",
+		"\"a ",
+		example_parserComment1,
+		"a#b\n# c",
+	}
+	for _, input := range inputs {
+		var want, got bytes.Buffer
+		wantErr := testDescribeParsers(&want, NewParser([]byte(input)))
+		gotErr := testDescribeParsers(&got, NewReaderParser(iotest.OneByteReader(strings.NewReader(input))))
+		if got.String() != want.String() || fmt.Sprint(gotErr) != fmt.Sprint(wantErr) {
+			t.Errorf("%q: got\n%s%v\nwant\n%s%v", input, &got, gotErr, &want, wantErr)
+		}
+	}
+}
+
+// Test that read errors are returned and stop parsing.
+func TestReaderParser_ReadError(t *testing.T) {
+	errRead := errors.New("read error")
+	parser := NewReaderParser(io.MultiReader(strings.NewReader("a "), iotest.ErrReader(errRead)))
+	if p, err := parser.Next(); p == nil || err != nil {
+		t.Errorf("Next() = (%v, %v) want (a, nil)", p, err)
+	}
+	for i := 0; i < 2; i++ {
+		if p, err := parser.Next(); p != nil || err != errRead {
+			t.Errorf("Next() = (%v, %v) want (nil, %v)", p, err, errRead)
+		}
+	}
+}
+
+func ExampleDecoder() {
+	dec := NewDecoder(strings.NewReader("{ fruit: orange price: 10.5 }\n{ fruit: apple price: 3 }"))
+	for {
+		var fruit struct {
+			Fruit string
+			Price float64
+		}
+		if err := dec.Decode(&fruit); err == io.EOF {
+			break
+		} else if err != nil {
+			fmt.Printf("error: %s\n", err)
+			return
+		}
+		fmt.Println(fruit.Fruit, fruit.Price)
+	}
+	// Output:
+	// orange 10.5
+	// apple 3
+}
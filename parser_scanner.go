@@ -12,6 +12,15 @@ func NewParserScanner(parser Parser) *ParserScanner {
 	return &ParserScanner{parser, nil, new(errorSink)}
 }
 
+// Create a new scanner operating on parser that recovers from parse errors.
+// After a parse error the parser resynchronises by skipping to the next key in
+// a dictionary or the next value in a list or at the root level and scanning
+// continues. Err returns all parse errors as an ErrorList. Use SubScanner to
+// descend into sub parsers while collecting errors in the same list.
+func NewRecoveringParserScanner(parser Parser) *ParserScanner {
+	return &ParserScanner{parser, nil, &errorSink{recover: true}}
+}
+
 // Create a new scanner operating on parser.
 // Errors are sent to the specified error sink.
 func newParserScannerErrorSink(parser Parser, es *errorSink) *ParserScanner {
@@ -21,15 +30,27 @@ func newParserScannerErrorSink(parser Parser, es *errorSink) *ParserScanner {
 // Scan the parser for a sub parser.
 // Returns true if a sub parser was found.
 func (scanner *ParserScanner) Scan() bool {
-	err := scanner.es.err()
-	if err == nil {
+	for scanner.es.ok() {
+		var err error
 		scanner.subparser, err = scanner.parser.Next()
+		if err == nil {
+			return scanner.subparser != nil
+		}
 		scanner.es.send(err)
+		r, ok := scanner.parser.(resyncer)
+		if !ok || !scanner.es.recover || !scanner.es.ok() {
+			break
+		}
+		r.resync()
 	}
-	if err != nil || scanner.subparser == nil {
-		return false
-	}
-	return true
+	return false
+}
+
+// Create a new scanner operating on the previously scanned sub parser.
+// The new scanner shares errors with this scanner. It must only be called
+// after Scan() has returned true.
+func (scanner *ParserScanner) SubScanner() *ParserScanner {
+	return newParserScannerErrorSink(scanner.subparser, scanner.es)
 }
 
 // Returns the previously scanned sub parser.
@@ -40,7 +61,8 @@ func (scanner *ParserScanner) SubParser() Parser {
 	return nil
 }
 
-// Returns the first error that occured while scanning.
+// Returns the first error that occured while scanning or an ErrorList for
+// recovering scanners.
 // This should be called after Scan() has returned false to check
 // for errors.
 func (scanner *ParserScanner) Err() error {
@@ -53,3 +75,8 @@ func (scanner *ParserScanner) Err() error {
 func (scanner *ParserScanner) InjectError(err error) {
 	scanner.es.send(err)
 }
+
+// Implemented by parsers that can resynchronise after a parse error.
+type resyncer interface {
+	resync()
+}
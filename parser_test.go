@@ -2,9 +2,12 @@ package pot
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func testParseString(pot string) {
@@ -45,13 +48,13 @@ func testParseDescent(wr io.Writer, parser Parser) error {
 	return scanner.Err()
 }
 
-func Example_ParserDict1() {
+func Example_parserDict1() {
 	testParse(NewDictParser([]byte("{ fruit: orange price: 10.5 }")))
 	// Output:
 	// fruit: orange price: 10.5
 }
 
-func Example_ParserDict2() {
+func Example_parserDict2() {
 	testParseString("{fruit:orange price:10.5}")
 	// Output:
 	// { fruit: orange price: 10.5 }
@@ -64,103 +67,219 @@ var example_ParserDict3 = `
           foods:        [ "dry grass" apples ] }
 `
 
-func Example_ParserDict3() {
+func Example_parserDict3() {
 	testParseString(example_ParserDict3 + "\r")
 	// Output:
 	// { animal: zebra class: mammal weight-range: [ 240kg 370kg ] foods: [ "dry grass" apples ] }
 }
 
-func Example_ParserDict4() {
+func Example_parserDict4() {
 	testParseString("{ a: { aa: [] ab: {} } b: \"\"}")
 	// Output:
 	// { a: { aa: [ ] ab: { } } b: "" }
 }
 
-func Example_ParserDict5() {
+func Example_parserDict5() {
 	testParseString("{ -invalid-key: 0 }")
 	// Output:
 	// error: 1:2: invalid character '-' in key
 }
 
-func Example_ParserDict6() {
+func Example_parserDict6() {
 	testParseString("{ : foo }")
 	// Output:
 	// error: 1:2: invalid character ':' in key
 }
 
-func Example_ParserDict7() {
+func Example_parserDict7() {
 	testParseString("{ foo: }")
 	// Output:
 	// error: 1:7: key without value in dictionary
 }
 
-func Example_ParserDict8() {
+func Example_parserDict8() {
 	testParseString("{ unterminated-key}")
 	// Output:
 	// error: 1:18: end of input while parsing key
 }
 
-func Example_ParserList1() {
+func Example_parserDict9() {
+	testParseString("{ zebra: z Zulu: Z x9: 9 }")
+	// Output:
+	// { zebra: z Zulu: Z x9: 9 }
+}
+
+func Example_parserDict10() {
+	testParse(NewParserWithOptions([]byte("{ _id: 1 server.port: 80 smörgås: 2 }"), &ParserOptions{ExtendedKeys: true}))
+	// Output:
+	// { _id: 1 server.port: 80 smörgås: 2 }
+}
+
+func Example_parserDict11() {
+	testParseString("{ smörgås: 2 }")
+	// Output:
+	// error: 1:4: invalid character 'ö' in key
+}
+
+func Example_parserList1() {
 	testParse(NewListParser([]byte("[ unterminated\\ block")))
 	// Output:
 	// error: 1:21: end of input while parsing '[]' block
 }
 
-func Example_ParserString1() {
+func Example_parserString1() {
 	testParseString("\"this is a long string\"")
 	// Output:
 	// "this is a long string"
 }
 
-func Example_ParserString2() {
+func Example_parserString2() {
 	testParseString("this\\ is\\ a\\ long\\ string")
 	// Output:
 	// "this is a long string"
 }
 
-func Example_ParserString3() {
+func Example_parserString3() {
 	testParseString("\"unterminated\\ quote")
 	// Output:
 	// error: 1:20: miss-matched quotes in string
 }
 
-func Example_ParserString4() {
+func Example_parserString4() {
 	testParseString("\"escape codes: \"\\{\\}\\[\\]\\:\\ \\\\\\\"\\n\\r\\tthe-end")
 	// Output:
 	// "escape codes: {}[]: \\\"\n\r\tthe-end"
 }
 
-func Example_ParserString5() {
+func Example_parserString5() {
 	testParseString("invalid-escape-code-\\m")
 	// Output:
 	// error: 1:21: invalid escape code \m
 }
 
-func Example_ParserString6() {
+func Example_parserString6() {
 	testParseString("unterminated-escape-code-\\")
 	// Output:
 	// error: 1:26: unterminated escape code in string
 }
 
-func Example_ParserString7() {
+func Example_parserString7() {
 	testParseString("]")
 	// Output:
 	// error: 1:0: invalid character ']' in string
 }
 
-func Example_ParserString8() {
+func Example_parserString8() {
 	testParseString("\nunescaped-or-unquoted-colon-in-string:")
 	// Output:
 	// error: 2:37: invalid character ':' in string
 }
 
-// Test that stripping space from the right does not strip data that has already been consumed.
-func TestParserBuf_TrimSpaceRight(t *testing.T) {
-	buf := newParserBuf([]byte("    "))
-	buf.trimBytesLeft(2)
-	buf.trimSpaceRight()
-	if buf.location.Column != 2 || len(buf.bytes) != 0 {
-		t.Errorf("column = %d, len = %d", buf.location.Column, len(buf.bytes))
+func Example_parserString9() {
+	testParseString("escaped\\n\\\"quotes\\\" [ list ]")
+	// Output:
+	// escaped\n\"quotes\" [ list ]
+}
+
+func Example_parserString10() {
+	testParseString("[ a\\n b\\: c ]")
+	// Output:
+	// [ a\n "b:" c ]
+}
+
+func Example_parserString11() {
+	testParseString("[ \\t \" \\t\" ]")
+	// Output:
+	// [ \t " \t" ]
+}
+
+var example_parserComment1 = `# Comment before dictionary.
+{ # Comment before key.
+  a: b # Comment after value.
+  c: [ d "#e" \#f # ] } "
+  ] # Comment after list.
+  g:
+  # Comment between key and value.
+  h
+} # Comment after dictionary.
+"#i"#j`
+
+func Example_parserComment1() {
+	testParseString(example_parserComment1)
+	// Output:
+	// { a: b c: [ d "#e" "#f" ] g: h } "#i"
+}
+
+func Example_parserComment2() {
+	testParse(NewDictParser([]byte("{ a: b } # Comment after dictionary.")))
+	// Output:
+	// a: b
+}
+
+func ExampleDict_All() {
+	dict := NewDictParser([]byte("{ fruit: orange price: 10.5 tags: [ sweet round ] }"))
+	for key, value := range dict.All() {
+		fmt.Printf("%s %s\n", key, value.Name())
+	}
+	if err := dict.Err(); err != nil {
+		fmt.Printf("error: %s\n", err)
+	}
+	// Output:
+	// fruit: string
+	// price: string
+	// tags: list
+}
+
+func ExampleList_All() {
+	list := NewListParser([]byte("[ a { b: c } d: ]"))
+	for value := range list.All() {
+		fmt.Println(value.Name())
+	}
+	if err := list.Err(); err != nil {
+		fmt.Printf("error: %s\n", err)
+	}
+	// Output:
+	// string
+	// dictionary
+	// error: 1:14: invalid character ':' in string
+}
+
+func Test_ParserAll(t *testing.T) {
+	root := NewParser([]byte("a [ b ] { c: d e: f } g")).(*Root)
+	var names []string
+	for value := range root.All() {
+		names = append(names, value.Name())
+		if dict, ok := value.(*Dict); ok {
+			for key := range dict.All() {
+				names = append(names, key.Name())
+				break
+			}
+			// Iteration resumes after breaking out of the loop.
+			for key, value := range dict.All() {
+				names = append(names, key.Name(), value.Name())
+			}
+		}
+		if len(names) > 5 {
+			break
+		}
+	}
+	if s := strings.Join(names, " "); s != "string list dictionary dictionary-key dictionary-key string" {
+		t.Errorf("names = %q", s)
+	}
+	if err := root.Err(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	for value := range root.All() {
+		if s := string(value.Bytes()); s != "g" {
+			t.Errorf("value = %q want \"g\"", s)
+		}
+	}
+
+	dict := NewDictParser([]byte("{ a: b c: }"))
+	for range dict.All() {
+	}
+	if err := dict.Err(); !errors.Is(err, ErrMissingValue) {
+		t.Errorf("error = %v want %v", err, ErrMissingValue)
 	}
 }
 
@@ -203,6 +322,133 @@ func Test_ParserBytesAndLocationFunctions(t *testing.T) {
 	testParserBytesAndLocationFunctions(t, NewParserScanner(parser))
 }
 
+// Test the standard and extended key character sets.
+func Test_ValidKeyRune(t *testing.T) {
+	tests := []struct {
+		key      string
+		standard bool
+		extended bool
+	}{
+		{"az-AZ-09", true, true},
+		{"9lives", true, true},
+		{"-a", false, false},
+		{"a_b", false, true},
+		{"_a", false, true},
+		{"a.b.c", false, true},
+		{".a", false, false},
+		{"ключ", false, true},
+		{"a٣", false, true},
+		{"a b", false, false},
+		{"a\xff", false, false},
+	}
+	for _, test := range tests {
+		if got := invalidKeyIndex([]byte(test.key), false) < 0; got != test.standard {
+			t.Errorf("standard key %q valid = %t", test.key, got)
+		}
+		if got := invalidKeyIndex([]byte(test.key), true) < 0; got != test.extended {
+			t.Errorf("extended key %q valid = %t", test.key, got)
+		}
+	}
+}
+
+// Test that exceeding parser limits produce errors of the expected kind.
+func Test_ParserOptionsLimits(t *testing.T) {
+	nested := func(n int) string {
+		return strings.Repeat("[", n) + strings.Repeat("]", n)
+	}
+	tests := []struct {
+		input   string
+		options ParserOptions
+		err     string
+		kind    ErrorKind
+	}{
+		{"{ a: [ b ] }", ParserOptions{MaxDepth: 2}, "", ErrOther},
+		{"{ a: [ { b: c } ] }", ParserOptions{MaxDepth: 2}, "1:7: nesting depth exceeds limit of 2", ErrDepthLimit},
+		{"[[[[[[]]]]]]", ParserOptions{MaxDepth: 3}, "1:3: nesting depth exceeds limit of 3", ErrDepthLimit},
+		{nested(DefaultMaxDepth), ParserOptions{}, "", ErrOther},
+		{nested(DefaultMaxDepth + 1), ParserOptions{}, "1:10000: nesting depth exceeds limit of 10000", ErrDepthLimit},
+		{nested(DefaultMaxDepth + 1), ParserOptions{MaxDepth: -1}, "", ErrOther},
+		{"abc \"a\\tb\"", ParserOptions{MaxStringLength: 6}, "", ErrOther},
+		{"abc \"a b c\"", ParserOptions{MaxStringLength: 6}, "1:4: string length exceeds limit of 6 bytes", ErrStringLimit},
+		{"{ long-key: a }", ParserOptions{MaxStringLength: 6}, "1:2: string length exceeds limit of 6 bytes", ErrStringLimit},
+		{"{ a: b c: d } [ a b ]", ParserOptions{MaxEntries: 2}, "", ErrOther},
+		{"{ a: b c: d e: f }", ParserOptions{MaxEntries: 2}, "1:12: number of dictionary entries exceeds limit of 2", ErrEntryLimit},
+		{"[ a b c ]", ParserOptions{MaxEntries: 2}, "1:6: number of list values exceeds limit of 2", ErrEntryLimit},
+		{"a b\ncd", ParserOptions{MaxInputSize: 7}, "", ErrOther},
+		{"a b\ncdef", ParserOptions{MaxInputSize: 7}, "2:3: input size exceeds limit of 7 bytes", ErrInputLimit},
+	}
+	for _, test := range tests {
+		options := test.options
+		parsers := []Parser{
+			NewParserWithOptions([]byte(test.input), &options),
+			NewReaderParserWithOptions(iotest.OneByteReader(strings.NewReader(test.input)), &options),
+		}
+		for _, parser := range parsers {
+			err := testParseDescent(io.Discard, parser)
+			if fmt.Sprint(err) != fmt.Sprint(test.err) && !(err == nil && test.err == "") {
+				t.Errorf("%q: error %v want %s", test.input, err, test.err)
+			}
+			if err != nil && !errors.Is(err, test.kind) {
+				t.Errorf("%q: error kind %q want %q", test.input, err.(*ParseError).Kind, test.kind)
+			}
+		}
+	}
+
+	// Limits must not prevent recovering parsers from making progress.
+	options := ParserOptions{MaxEntries: 1}
+	scanner := NewRecoveringParserScanner(NewParserWithOptions([]byte("{ a: b c: d e: [ f g ] }"), &options))
+	for scanner.Scan() {
+		for sub := scanner.SubScanner(); sub.Scan(); {
+		}
+	}
+	if list, ok := scanner.Err().(ErrorList); !ok || len(list) != 2 {
+		t.Errorf("Err() = %v want 2 errors", scanner.Err())
+	}
+}
+
+// Test that parser spans cover the text of the parsed values.
+func Test_ParserSpan(t *testing.T) {
+	text := []byte("{ key: \"a \\n b\"\n  list: [ x\ty {} ] }# c\n\"\"")
+	want := []struct {
+		span  Span
+		bytes string
+	}{
+		{Span{Location{0, 0}, Location{1, 20}, 0, 36}, "{ key: \"a \\n b\"\n  list: [ x\ty {} ] }"},
+		{Span{Location{0, 2}, Location{0, 5}, 2, 5}, "key"},
+		{Span{Location{0, 7}, Location{0, 15}, 7, 15}, "\"a \\n b\""},
+		{Span{Location{1, 2}, Location{1, 6}, 18, 22}, "list"},
+		{Span{Location{1, 8}, Location{1, 18}, 24, 34}, "[ x\ty {} ]"},
+		{Span{Location{1, 10}, Location{1, 11}, 26, 27}, "x"},
+		{Span{Location{1, 12}, Location{1, 13}, 28, 29}, "y"},
+		{Span{Location{1, 14}, Location{1, 16}, 30, 32}, "{}"},
+		{Span{Location{2, 0}, Location{2, 2}, 40, 42}, "\"\""},
+	}
+	var spans []Span
+	var walk func(parser Parser) error
+	walk = func(parser Parser) error {
+		scanner := NewParserScanner(parser)
+		for scanner.Scan() {
+			spans = append(spans, scanner.SubParser().(Spanner).Span())
+			walk(scanner.SubParser())
+		}
+		return scanner.Err()
+	}
+	if err := walk(NewParser(text)); err != nil {
+		t.Fatal(err)
+	}
+	if len(spans) != len(want) {
+		t.Fatalf("got %d spans want %d", len(spans), len(want))
+	}
+	for i, span := range spans {
+		if span != want[i].span || string(span.Bytes(text)) != want[i].bytes {
+			t.Errorf("span %d = %v %q want %v %q", i, span, span.Bytes(text), want[i].span, want[i].bytes)
+		}
+	}
+	if span := NewParser(text).(Spanner).Span(); span.EndOffset != len(text) || span.End != (Location{2, 2}) {
+		t.Errorf("Root.Span() = %v", span)
+	}
+}
+
 // Test that injecting an error aborts parser iteration.
 func TestParserScanner_InjectError(t *testing.T) {
 	scanner := NewParserScanner(NewListParser([]byte("[ this is a list ]")))
@@ -278,3 +524,53 @@ func Test_ParserCoverage(t *testing.T) {
 		t.Errorf("ParserScanner.SubParser() = %v want nil", parser)
 	}
 }
+
+// Test that a recovering scanner collects errors from sub scanners.
+func TestParserScanner_Recover(t *testing.T) {
+	var strs []string
+	scanner := NewRecoveringParserScanner(NewParser([]byte("a [ b ] c ] [ d : e ]")))
+	for scanner.Scan() {
+		if _, ok := scanner.SubParser().(*List); ok {
+			subscanner := scanner.SubScanner()
+			for subscanner.Scan() {
+				strs = append(strs, string(subscanner.SubParser().Bytes()))
+			}
+		} else {
+			strs = append(strs, string(scanner.SubParser().Bytes()))
+		}
+	}
+	if s := fmt.Sprint(strs); s != "[a b c d e]" {
+		t.Errorf("scanned %s want [a b c d e]", s)
+	}
+	if err := scanner.Err(); err == nil || err.Error() != "1:10: invalid character ']' in string (and 1 more errors)" {
+		t.Errorf("Err() = %v", err)
+	}
+}
+
+// Benchmark parsing deeply nested blocks. The time per nesting level should
+// stay the same as the depth grows.
+func BenchmarkParser_DeepNesting(b *testing.B) {
+	options := ParserOptions{MaxDepth: -1}
+	for _, depth := range []int{10, 100, 1000, 10000} {
+		pot := []byte(strings.Repeat("{ a: [ b ", depth) + strings.Repeat("] }", depth))
+		b.Run(fmt.Sprint(depth), func(b *testing.B) {
+			b.SetBytes(int64(len(pot)))
+			for i := 0; i < b.N; i++ {
+				if err := testParseDescent(io.Discard, NewParserWithOptions(pot, &options)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// Benchmark parsing a large flat dictionary.
+func BenchmarkParser_Flat(b *testing.B) {
+	pot := []byte("{" + strings.Repeat(" key: \"some value\" list: [ a b c ]\n", 10000) + "}")
+	b.SetBytes(int64(len(pot)))
+	for i := 0; i < b.N; i++ {
+		if err := testParseDescent(io.Discard, NewParser(pot)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package pot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Compiled path expression selecting values of a document.
+//
+// A path is a sequence of steps applied to a set of document nodes, each step
+// selecting new nodes from the ones selected by the previous step:
+//
+//	key     Values of all entries with the key of dictionaries.
+//	key#n   Value of entry n with the key of dictionaries, counting from zero.
+//	[n]     Item n of lists, counting from zero. Negative indices count from the end.
+//	*       Values of all entries of dictionaries and all items of lists.
+//	[*]     Same as '*'.
+//	..      Apply the following step to the nodes and all their descendants.
+//	#n      Node n of the nodes the path is applied to, such as the root level
+//	        values of a document, counting from zero. Only valid as the first step.
+//
+// Steps are separated by '.', which may be left out before '[' and for the
+// first step. Characters with a special meaning in paths are escaped with '\'
+// in keys. An empty path selects the nodes it's applied to.
+//
+// Example:
+//
+//	servers[2].ports   Item 2 of the servers list, followed by its ports.
+//	route#3.target     Target of the fourth route entry.
+//	..name             Name entries at any depth.
+//	#1.name            Name of the second root level value.
+type Path struct {
+	expr  string
+	steps []pathStep
+}
+
+// Kind of path step.
+type pathStepKind int
+
+const (
+	pathKey      pathStepKind = iota // Dictionary entries by key.
+	pathKeyIndex                     // Dictionary entry by key and index among entries with the key.
+	pathIndex                        // List item by index.
+	pathWildcard                     // All dictionary entries and list items.
+	pathRoot                         // Node by index among the nodes the path is applied to.
+)
+
+// Step of a path expression.
+type pathStep struct {
+	kind    pathStepKind
+	descend bool // Apply the step to the nodes and all their descendants.
+	key     string
+	index   int
+}
+
+// Error returned for invalid path expressions.
+type PathError struct {
+	Path    string // Path expression.
+	Offset  int    // Byte offset of the error in the path expression.
+	Message string
+}
+
+// Implements error.
+func (err *PathError) Error() string {
+	return fmt.Sprintf("path %q:%d: %s", err.Path, err.Offset, err.Message)
+}
+
+// Compile a path expression.
+// Returns the path or a *PathError.
+func CompilePath(expr string) (*Path, error) {
+	c := pathCompiler{expr: expr}
+	steps, err := c.compile()
+	if err != nil {
+		return nil, err
+	}
+	return &Path{expr, steps}, nil
+}
+
+// Implements fmt.Stringer.
+func (path *Path) String() string {
+	return path.expr
+}
+
+// Select the nodes matched by the path in document order.
+// The path is applied to each of the supplied nodes.
+func (path *Path) Select(nodes ...*Node) []*Node {
+	for _, step := range path.steps {
+		nodes = step.apply(nodes)
+	}
+	return nodes
+}
+
+// Select the nodes matched by path from the values produced by parser.
+//
+// Dict, List and String parsers produce a single value while other parsers,
+// such as the root level parsers, produce all the values returned by Next.
+// Returns the nodes, a *PathError or the first parse error.
+func Query(parser Parser, path string) ([]*Node, error) {
+	p, err := CompilePath(path)
+	if err != nil {
+		return nil, err
+	}
+	var nodes []*Node
+	switch parser.(type) {
+	case *Dict, *List, *String:
+		node := new(Node)
+		if err = node.UnmarshalPOT(parser); err != nil {
+			return nil, err
+		}
+		nodes = []*Node{node}
+	default:
+		if nodes, err = parseNodes(parser); err != nil {
+			return nil, err
+		}
+	}
+	return p.Select(nodes...), nil
+}
+
+// Select the nodes matched by path from the node.
+// Returns the nodes or a *PathError.
+func (node *Node) Query(path string) ([]*Node, error) {
+	p, err := CompilePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return p.Select(node), nil
+}
+
+// Apply the step to nodes.
+func (step *pathStep) apply(nodes []*Node) []*Node {
+	if step.kind == pathRoot {
+		if step.index < len(nodes) {
+			return nodes[step.index : step.index+1]
+		}
+		return nil
+	}
+	if step.descend {
+		var all []*Node
+		for _, node := range nodes {
+			all = appendDescendants(all, node)
+		}
+		nodes = all
+	}
+
+	var selected []*Node
+	for _, node := range nodes {
+		selected = step.appendMatches(selected, node)
+	}
+	if step.descend {
+		selected = uniqueNodes(selected)
+	}
+	return selected
+}
+
+// Append the nodes matched by the step from node to selected.
+func (step *pathStep) appendMatches(selected []*Node, node *Node) []*Node {
+	switch step.kind {
+	case pathKey:
+		return append(selected, node.LookupAll(step.key)...)
+	case pathKeyIndex:
+		if values := node.LookupAll(step.key); step.index < len(values) {
+			return append(selected, values[step.index])
+		}
+	case pathIndex:
+		i := step.index
+		if i < 0 {
+			i += len(node.Items)
+		}
+		if i >= 0 && i < len(node.Items) {
+			return append(selected, node.Items[i])
+		}
+	case pathWildcard:
+		for _, entry := range node.Entries {
+			selected = append(selected, entry.Value)
+		}
+		return append(selected, node.Items...)
+	}
+	return selected
+}
+
+// Append node and all its descendants in document order to nodes.
+func appendDescendants(nodes []*Node, node *Node) []*Node {
+	nodes = append(nodes, node)
+	for _, entry := range node.Entries {
+		nodes = appendDescendants(nodes, entry.Value)
+	}
+	for _, item := range node.Items {
+		nodes = appendDescendants(nodes, item)
+	}
+	return nodes
+}
+
+// Remove duplicate nodes keeping the first occurrence.
+func uniqueNodes(nodes []*Node) []*Node {
+	seen := make(map[*Node]bool, len(nodes))
+	unique := nodes[:0]
+	for _, node := range nodes {
+		if !seen[node] {
+			seen[node] = true
+			unique = append(unique, node)
+		}
+	}
+	return unique
+}
+
+// Compiler of path expressions.
+type pathCompiler struct {
+	expr string
+	pos  int
+}
+
+// Format a path error at the current position.
+func (c *pathCompiler) errorf(format string, a ...interface{}) error {
+	return &PathError{c.expr, c.pos, fmt.Sprintf(format, a...)}
+}
+
+// Compile the path expression into steps.
+func (c *pathCompiler) compile() ([]pathStep, error) {
+	var steps []pathStep
+	for c.pos < len(c.expr) {
+		step := pathStep{}
+		switch {
+		case strings.HasPrefix(c.expr[c.pos:], ".."):
+			step.descend = true
+			c.pos += 2
+		case c.expr[c.pos] == '.':
+			if c.pos++; len(steps) == 0 && c.pos == len(c.expr) {
+				return steps, nil
+			}
+		case c.expr[c.pos] != '[' && len(steps) > 0:
+			return nil, c.errorf("expected '.' or '['")
+		}
+		if c.pos == len(c.expr) {
+			return nil, c.errorf("missing step at end of path")
+		}
+
+		var err error
+		switch {
+		case c.expr[c.pos] == '#' && c.pos == 0:
+			err = c.compileRoot(&step)
+		case c.expr[c.pos] == '[':
+			err = c.compileIndex(&step)
+		default:
+			err = c.compileKey(&step)
+		}
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// Compile a '#n' step.
+func (c *pathCompiler) compileRoot(step *pathStep) error {
+	c.pos++
+	end := strings.IndexAny(c.expr[c.pos:], ".[")
+	if end < 0 {
+		end = len(c.expr) - c.pos
+	}
+	s := c.expr[c.pos : c.pos+end]
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return c.errorf("invalid root index %q", s)
+	}
+	step.kind, step.index = pathRoot, n
+	c.pos += end
+	return nil
+}
+
+// Compile a '[n]' or '[*]' step.
+func (c *pathCompiler) compileIndex(step *pathStep) error {
+	c.pos++
+	end := strings.IndexByte(c.expr[c.pos:], ']')
+	if end < 0 {
+		return c.errorf("missing ']'")
+	}
+	s := c.expr[c.pos : c.pos+end]
+	if s == "*" {
+		step.kind = pathWildcard
+	} else {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return c.errorf("invalid list index %q", s)
+		}
+		step.kind, step.index = pathIndex, n
+	}
+	c.pos += end + 1
+	return nil
+}
+
+// Compile a 'key', 'key#n' or '*' step.
+func (c *pathCompiler) compileKey(step *pathStep) error {
+	start := c.pos
+	var key strings.Builder
+	escaped := false
+loop:
+	for ; c.pos < len(c.expr); c.pos++ {
+		ch := c.expr[c.pos]
+		switch {
+		case escaped:
+			escaped = false
+		case ch == '\\':
+			escaped = true
+			continue
+		case ch == '.' || ch == '[' || ch == ']' || ch == '#':
+			break loop
+		}
+		key.WriteByte(ch)
+	}
+	switch {
+	case escaped:
+		return c.errorf("unterminated escape code")
+	case key.Len() == 0:
+		return c.errorf("missing key")
+	case c.expr[start:c.pos] == "*":
+		step.kind = pathWildcard
+		return nil
+	}
+
+	step.kind, step.key = pathKey, key.String()
+	if c.pos < len(c.expr) && c.expr[c.pos] == '#' {
+		c.pos++
+		end := strings.IndexAny(c.expr[c.pos:], ".[")
+		if end < 0 {
+			end = len(c.expr) - c.pos
+		}
+		s := c.expr[c.pos : c.pos+end]
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return c.errorf("invalid key index %q", s)
+		}
+		step.kind, step.index = pathKeyIndex, n
+		c.pos += end
+	}
+	return nil
+}
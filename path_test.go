@@ -0,0 +1,128 @@
+package pot
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func ExampleQuery() {
+	pot := []byte(`{
+    servers: [
+        { name: a ports: [ 80 443 ] }
+        { name: b ports: [ 8080 ] }
+    ]
+}`)
+	nodes, err := Query(NewParser(pot), "servers[1].ports[*]")
+	if err != nil {
+		fmt.Println(err)
+	}
+	for _, node := range nodes {
+		fmt.Printf("%s: %s\n", node.Location, node)
+	}
+	// Output:
+	// 4:27: 8080
+}
+
+func TestPath_Select(t *testing.T) {
+	pot := `{
+	a: x
+	route: { to: r0 }
+	route: { to: r1 }
+	list: [ l0 [ l10 ] { to: l2 } ]
+	b: { a: z c: { a: w } }
+} last`
+	tests := []struct {
+		path   string
+		values string
+	}{
+		{"", "{ a: x ... } last"},
+		{".", "{ a: x ... } last"},
+		{"a", "x"},
+		{".a", "x"},
+		{"route", "{ to: r0 } { to: r1 }"},
+		{"route#1.to", "r1"},
+		{"route#2", ""},
+		{"route.to", "r0 r1"},
+		{"list[0]", "l0"},
+		{"list[-1].to", "l2"},
+		{"list.[1][0]", "l10"},
+		{"list[3]", ""},
+		{"list[*]", "l0 [ l10 ] { to: l2 }"},
+		{"list.*", "l0 [ l10 ] { to: l2 }"},
+		{"b.*", "z { a: w }"},
+		{`dot\.key`, "y"},
+		{"..to", "r0 r1 l2"},
+		{"b..a", "z w"},
+		{"..[0]", "l0 l10"},
+		{"x.y", ""},
+		{"#1", "last"},
+		{"#0.a", "x"},
+		{"#0.list[1][0]", "l10"},
+		{"#2", ""},
+	}
+	nodes, err := Parse([]byte(pot))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes[0].Append("dot.key", NewString("y"))
+	for _, test := range tests {
+		path, err := CompilePath(test.path)
+		if err != nil {
+			t.Errorf("%q: %s", test.path, err)
+			continue
+		}
+		var values []string
+		for _, node := range path.Select(nodes...) {
+			s := node.String()
+			if node.Kind == DictNode && len(node.Entries) > 2 {
+				s = fmt.Sprintf("{ %s: %s ... }", node.Entries[0].Key, node.Entries[0].Value)
+			}
+			values = append(values, s)
+		}
+		if s := strings.Join(values, " "); s != test.values {
+			t.Errorf("%q: selected %q want %q", test.path, s, test.values)
+		}
+	}
+}
+
+func TestCompilePath_Errors(t *testing.T) {
+	tests := []struct {
+		path string
+		err  string
+	}{
+		{"a..", `path "a..":3: missing step at end of path`},
+		{"a.", `path "a.":2: missing step at end of path`},
+		{"a[1", `path "a[1":2: missing ']'`},
+		{"a[x]", `path "a[x]":2: invalid list index "x"`},
+		{"a#x", `path "a#x":2: invalid key index "x"`},
+		{"a#-1", `path "a#-1":2: invalid key index "-1"`},
+		{"a\\", `path "a\\":2: unterminated escape code`},
+		{"a]", `path "a]":1: expected '.' or '['`},
+		{"#x", `path "#x":1: invalid root index "x"`},
+		{"#-1", `path "#-1":1: invalid root index "-1"`},
+		{"a.#1", `path "a.#1":2: missing key`},
+		{"..#1", `path "..#1":2: missing key`},
+	}
+	for _, test := range tests {
+		if _, err := CompilePath(test.path); err == nil || err.Error() != test.err {
+			t.Errorf("%q: error = %v want %s", test.path, err, test.err)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	dict := NewDictParser([]byte("{ a: { b: c } }"))
+	nodes, err := Query(dict, "a.b")
+	if err != nil || len(nodes) != 1 || nodes[0].Value != "c" || nodes[0].Location != (Location{0, 10}) {
+		t.Errorf("Query = %v, %v", nodes, err)
+	}
+	if _, err = Query(NewParser([]byte("{ a: }")), "a"); err == nil {
+		t.Errorf("expected parse error")
+	}
+	node := NewDict()
+	node.Append("a", NewList(NewString("x"), NewString("y")))
+	if nodes, err = node.Query("a[1]"); err != nil || len(nodes) != 1 || nodes[0].Value != "y" {
+		t.Errorf("Node.Query = %v, %v", nodes, err)
+	}
+}
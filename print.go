@@ -1,112 +1,335 @@
 package pot
 
-// Number of space characters used per indentation level.
-const indentSize = 4
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
 
-// Space character buffer used for indentation.
-var spaceBuffer []byte
-
-// Return a buffer of space characters corresponding to the specified
-// indentation level.
-func indentSpace(indentLevel int) []byte {
-	nbSpaces := indentLevel * indentSize
-	for i := len(spaceBuffer); i < nbSpaces; i++ {
-		spaceBuffer = append(spaceBuffer, ' ')
-	}
-	return spaceBuffer[:nbSpaces]
+// Printer formatting POT text with a configurable layout.
+//
+// By default dictionaries are broken over multiple lines with one entry per
+// line while lists and empty dictionaries are printed on a single line.
+// Setting a maximum line width instead prints any dictionary or list that fits
+// within the width on a single line and breaks the others with one entry or
+// value per line. Strings are never broken.
+type Printer struct {
+	Indent       int  // Number of spaces per indentation level, 4 if zero. Also the width of tabs.
+	IndentTabs   bool // Indent with tabs instead of spaces.
+	AlignKeys    bool // Align the values of consecutive dictionary entries printed on single lines.
+	MaxWidth     int  // Maximum line width or 0 to not limit the width.
+	Compact      bool // Print root level values on single lines.
+	FinalNewline bool // End the output with a new-line.
+	KeepLayout   bool // Keep comments, blank lines and line breaks of the text input, see PrintSyntax.
 }
 
+// Printer used by PrettyPrint.
+var prettyPrinter = Printer{AlignKeys: true}
+
+// Minimum width of aligned dictionary keys including indentation, the ':' and
+// padding.
+const minKeyWidth = 4
+
+// Markers starting the versions of merge conflicts and ending conflicts.
+const (
+	conflictOurs   = "<<<<<<< ours"
+	conflictBase   = "||||||| base"
+	conflictTheirs = "======="
+	conflictEnd    = ">>>>>>> theirs"
+)
+
 // Pretty print POT text buffer.
 // Returns a byte slice or an error on parsing errors.
 func PrettyPrint(pot []byte) ([]byte, error) {
-	buf := newPrintBuf(nil)
-	scanner := newParserScannerErrorSink(NewParser(pot), buf.errorSink())
-	for scanner.Scan() {
-		switch subparser := scanner.SubParser().(type) {
-		case *Dict:
-			prettyPrintDict(buf, subparser, 0)
-		case *List:
-			printList(buf, subparser)
-		case *String:
-			buf.printf("%s", subparser)
-		}
-		buf.term('\n')
-	}
-
-	buf.flush()
-	return buf.bytes(), buf.err()
+	return prettyPrinter.Print(pot)
+}
+
+// Format POT text buffer.
+// Returns a byte slice or an error on parsing errors.
+func (p *Printer) Print(pot []byte) ([]byte, error) {
+	if p.KeepLayout {
+		root, err := ParseSyntax(pot)
+		if err != nil {
+			return nil, err
+		}
+		return p.PrintSyntax(root)
+	}
+	nodes, err := Parse(pot)
+	if err != nil {
+		return nil, err
+	}
+	return p.PrintNodes(nodes)
+}
+
+// Format document nodes as POT text, one root level value for each node.
+// Returns a byte slice or an error on invalid nodes.
+func (p *Printer) PrintNodes(nodes []*Node) ([]byte, error) {
+	pp := printer{Printer: p}
+	for i, node := range nodes {
+		if i > 0 {
+			pp.buf.WriteByte('\n')
+		}
+		if err := pp.printValue(node, 0, 0); err != nil {
+			return nil, err
+		}
+	}
+	if p.FinalNewline && len(nodes) > 0 {
+		pp.buf.WriteByte('\n')
+	}
+	return pp.buf.Bytes(), nil
+}
+
+// State of a printer formatting document nodes.
+type printer struct {
+	*Printer
+	buf    bytes.Buffer
+	breaks map[*SyntaxNode]bool // Syntax nodes that must be broken over multiple lines.
+}
+
+// Get the number of columns per indentation level.
+func (p *printer) indentWidth() int {
+	if p.Indent > 0 {
+		return p.Indent
+	}
+	return 4
+}
+
+// Write indentation for the specified level.
+func (p *printer) indent(level int) {
+	if p.IndentTabs {
+		p.buf.WriteString(strings.Repeat("\t", level))
+	} else {
+		p.buf.WriteString(strings.Repeat(" ", level*p.indentWidth()))
+	}
 }
 
-// Pretty print dictionary.
-// Any errors are sent to the print buffer's error sink.
-func prettyPrintDict(buf *printBuf, dict *Dict, indentLevel int) {
-	var key *DictKey
-	spaces0 := indentSpace(indentLevel)
-	spaces1 := indentSpace(indentLevel + 1)
-
-	buf.write([]byte("{\n"))
-	scanner := newParserScannerErrorSink(dict, buf.errorSink())
-	for scanner.Scan() {
-		switch subparser := scanner.SubParser().(type) {
-		case *Dict:
-			if subparser.IsEmpty() {
-				buf.printf("%s%s\t{ }\n", spaces1, key)
-			} else {
-				buf.printf("%s%s ", spaces1, key)
-				prettyPrintDict(buf, subparser, indentLevel+1)
+// Print a value at the specified indentation level starting at column.
+func (p *printer) printValue(node *Node, level, column int) error {
+	if node == nil {
+		return fmt.Errorf("nil document node")
+	}
+	if node.Kind == ConflictNode {
+		return p.printConflict("", node, level)
+	}
+	if p.singleLine(node, level, column) {
+		return node.write(&p.buf)
+	}
+	if node.Kind == DictNode {
+		return p.printDict(node, level)
+	}
+	return p.printList(node, level)
+}
+
+// Check if node is printed on a single line when starting at column.
+func (p *printer) singleLine(node *Node, level, column int) bool {
+	switch {
+	case hasConflict(node):
+		return false
+	case p.Compact || node.Kind != DictNode && node.Kind != ListNode:
+		return true
+	case p.MaxWidth > 0:
+		return column+nodeWidth(node, p.MaxWidth-column) <= p.MaxWidth
+	case node.Kind == DictNode:
+		return level > 0 && len(node.Entries) == 0
+	}
+	return true
+}
+
+// Print a dictionary broken over multiple lines.
+func (p *printer) printDict(node *Node, level int) error {
+	p.buf.WriteString("{\n")
+
+	// Width of keys including indentation and ':'.
+	indentWidth := (level + 1) * p.indentWidth()
+	keyWidth := func(key string) int {
+		return indentWidth + utf8.RuneCountInString(key) + 1
+	}
+	alignWidth := minKeyWidth
+	if p.AlignKeys {
+		for _, entry := range node.Entries {
+			alignWidth = max(alignWidth, keyWidth(entry.Key)+1)
+		}
+	}
+
+	block := 0 // Start of entries printed on single lines.
+	for i, entry := range node.Entries {
+		if !validKey([]byte(entry.Key), true) {
+			return fmt.Errorf("invalid dictionary key %q", entry.Key)
+		}
+		if entry.Value == nil {
+			return fmt.Errorf("nil document node")
+		}
+		if entry.Value.Kind == ConflictNode {
+			if err := p.printEntries(node.Entries[block:i], level+1); err != nil {
+				return err
+			}
+			block = i + 1
+			if err := p.printConflict(entry.Key, entry.Value, level+1); err != nil {
+				return err
 			}
-		case *DictKey:
-			key = subparser
-		case *List:
-			buf.printf("%s%s\t", spaces1, key)
-			printList(buf, subparser)
-			buf.term('\n')
-		case *String:
-			buf.printf("%s%s\t%s\n", spaces1, key, subparser)
-		}
-	}
-	buf.write(spaces0)
-	buf.write([]byte("}")).term('\n')
+			p.buf.WriteByte('\n')
+			continue
+		}
+		column := keyWidth(entry.Key) + 1
+		if p.AlignKeys {
+			column = alignWidth
+		}
+		if p.singleLine(entry.Value, level+1, column) {
+			continue
+		}
+		if err := p.printEntries(node.Entries[block:i], level+1); err != nil {
+			return err
+		}
+		block = i + 1
+		p.indent(level + 1)
+		p.buf.WriteString(entry.Key)
+		p.buf.WriteString(": ")
+		if err := p.printValue(entry.Value, level+1, keyWidth(entry.Key)+1); err != nil {
+			return err
+		}
+		p.buf.WriteByte('\n')
+	}
+	if err := p.printEntries(node.Entries[block:], level+1); err != nil {
+		return err
+	}
+
+	p.indent(level)
+	p.buf.WriteByte('}')
+	return nil
 }
 
-// Print dictionary on a single line.
-// Any errors are sent to the print buffer's error sink.
-func printDict(buf *printBuf, dict *Dict) {
-	var key *DictKey
-
-	buf.write([]byte("{ "))
-	scanner := newParserScannerErrorSink(dict, buf.errorSink())
-	for scanner.Scan() {
-		switch subparser := scanner.SubParser().(type) {
-		case *Dict:
-			buf.printf("%s ", key)
-			printDict(buf, subparser)
-		case *DictKey:
-			key = subparser
-		case *List:
-			buf.printf("%s ", key)
-			printList(buf, subparser)
-		case *String:
-			buf.printf("%s %s ", key, subparser)
-		}
-	}
-	buf.write([]byte("}")).term(' ')
+// Print consecutive dictionary entries on single lines, aligning their values
+// if requested.
+func (p *printer) printEntries(entries []Entry, level int) error {
+	width := minKeyWidth
+	for _, entry := range entries {
+		width = max(width, level*p.indentWidth()+utf8.RuneCountInString(entry.Key)+2)
+	}
+	for _, entry := range entries {
+		p.indent(level)
+		p.buf.WriteString(entry.Key)
+		p.buf.WriteByte(':')
+		pad := 1
+		if p.AlignKeys {
+			pad = width - level*p.indentWidth() - utf8.RuneCountInString(entry.Key) - 1
+		}
+		p.buf.WriteString(strings.Repeat(" ", pad))
+		if err := entry.Value.write(&p.buf); err != nil {
+			return err
+		}
+		p.buf.WriteByte('\n')
+	}
+	return nil
+}
+
+// Print a list broken over multiple lines.
+func (p *printer) printList(node *Node, level int) error {
+	p.buf.WriteString("[\n")
+	for _, item := range node.Items {
+		if item != nil && item.Kind == ConflictNode {
+			if err := p.printConflict("", item, level+1); err != nil {
+				return err
+			}
+			p.buf.WriteByte('\n')
+			continue
+		}
+		p.indent(level + 1)
+		if err := p.printValue(item, level+1, (level+1)*p.indentWidth()); err != nil {
+			return err
+		}
+		p.buf.WriteByte('\n')
+	}
+	p.indent(level)
+	p.buf.WriteByte(']')
+	return nil
 }
 
-// Print list on a single line.
-// Any errors are sent to the print buffer's error sink.
-func printList(buf *printBuf, list *List) {
-	buf.write([]byte("[ "))
-	scanner := newParserScannerErrorSink(list, buf.errorSink())
-	for scanner.Scan() {
-		switch subparser := scanner.SubParser().(type) {
-		case *Dict:
-			printDict(buf, subparser)
-		case *List:
-			printList(buf, subparser)
-		case *String:
-			buf.printf("%s ", subparser)
-		}
-	}
-	buf.write([]byte("]")).term(' ')
+// Print a merge conflict with its versions between conflict markers at the
+// start of lines. Versions are printed as dictionary entries with key or as
+// list items if key is empty.
+func (p *printer) printConflict(key string, node *Node, level int) error {
+	if node.Conflict == nil {
+		return fmt.Errorf("conflict node without versions")
+	}
+	versions := []struct {
+		marker string
+		nodes  []*Node
+	}{
+		{conflictOurs, node.Conflict.Ours},
+		{conflictBase, node.Conflict.Base},
+		{conflictTheirs, node.Conflict.Theirs},
+	}
+	for _, version := range versions {
+		p.buf.WriteString(version.marker)
+		p.buf.WriteByte('\n')
+		for _, value := range version.nodes {
+			if value != nil && value.Kind == ConflictNode {
+				return fmt.Errorf("nested conflict node")
+			}
+			p.indent(level)
+			column := level * p.indentWidth()
+			if key != "" {
+				p.buf.WriteString(key)
+				p.buf.WriteString(": ")
+				column += utf8.RuneCountInString(key) + 2
+			}
+			if err := p.printValue(value, level, column); err != nil {
+				return err
+			}
+			p.buf.WriteByte('\n')
+		}
+	}
+	p.buf.WriteString(conflictEnd)
+	return nil
+}
+
+// Check if node holds a merge conflict at any depth.
+func hasConflict(node *Node) bool {
+	if node == nil {
+		return false
+	}
+	if node.Kind == ConflictNode {
+		return true
+	}
+	for _, entry := range node.Entries {
+		if hasConflict(entry.Value) {
+			return true
+		}
+	}
+	for _, item := range node.Items {
+		if hasConflict(item) {
+			return true
+		}
+	}
+	return false
+}
+
+// Get the width of node printed on a single line.
+// Counting stops once the width exceeds limit.
+func nodeWidth(node *Node, limit int) int {
+	if node == nil {
+		return 0
+	}
+	switch node.Kind {
+	case DictNode:
+		width := len("{ }")
+		for _, entry := range node.Entries {
+			if width > limit {
+				break
+			}
+			width += utf8.RuneCountInString(entry.Key) + len(": ") + nodeWidth(entry.Value, limit-width) + 1
+		}
+		return width
+	case ListNode:
+		width := len("[ ]")
+		for _, item := range node.Items {
+			if width > limit {
+				break
+			}
+			width += nodeWidth(item, limit-width) + 1
+		}
+		return width
+	}
+	return utf8.RuneCountInString(formatString([]byte(node.Value)))
 }
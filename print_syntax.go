@@ -0,0 +1,300 @@
+package pot
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Format a syntax tree as POT text keeping its layout where it matters.
+//
+// Comments are kept on their own lines before the entries and values they
+// precede or at the end of the line they were on. Blank lines separating groups
+// of entries or values are kept, but collapsed into single blank lines. Values
+// written on the same line of a list or at the root level are kept on the same
+// line, and lists or dictionaries in lists that were broken over multiple lines
+// stay broken. Indentation and key alignment are normalised according to the
+// printer options, while MaxWidth and Compact do not apply. Keys and strings
+// are kept as written.
+func (p *Printer) PrintSyntax(node *SyntaxNode) ([]byte, error) {
+	pp := printer{Printer: p, breaks: make(map[*SyntaxNode]bool)}
+	var err error
+	if node.Kind == SyntaxRoot {
+		err = pp.printBody(node, 0)
+	} else {
+		err = pp.printSyntaxValue(node, 0, layoutRoot)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.FinalNewline && pp.buf.Len() > 0 {
+		pp.buf.WriteByte('\n')
+	}
+	return pp.buf.Bytes(), nil
+}
+
+// Context of a value printed in layout preserving mode.
+type layoutContext int
+
+const (
+	layoutRoot  layoutContext = iota // Root level value.
+	layoutEntry                      // Value of a dictionary entry.
+	layoutItem                       // Value in a list.
+)
+
+// Line of the body of a Root, Dict or List node in layout preserving mode.
+// A line holds either a dictionary entry, values of a list or the root level
+// or only a comment.
+type layoutLine struct {
+	key     *SyntaxNode   // Key of dictionary entries.
+	values  []*SyntaxNode // Values on the line.
+	comment *SyntaxNode   // Comment ending the line.
+	blank   bool          // Preceded by a blank line.
+}
+
+// Print a syntax value in layout preserving mode at the specified indentation
+// level.
+func (p *printer) printSyntaxValue(node *SyntaxNode, level int, context layoutContext) error {
+	switch node.Kind {
+	case SyntaxString:
+		p.buf.Write(node.Raw)
+		return nil
+	case SyntaxDict, SyntaxList:
+		if len(node.Children) < 2 {
+			return fmt.Errorf("%s node without delimiters", node.Kind)
+		}
+		if !p.multiLine(node, context) {
+			p.writeInline(node)
+			return nil
+		}
+		p.buf.Write(node.Children[0].Raw)
+		if err := p.printBody(node, level+1); err != nil {
+			return err
+		}
+		p.buf.WriteByte('\n')
+		p.indent(level)
+		p.buf.Write(node.Children[len(node.Children)-1].Raw)
+		return nil
+	}
+	return fmt.Errorf("invalid %s node in place of value", node.Kind)
+}
+
+// Check if a value is printed over multiple lines in the specified context.
+// Dictionaries at the root level or in entries are broken unless empty, like
+// PrettyPrint does, while other dictionaries and lists are only broken if they
+// were broken in the text input or contain comments.
+func (p *printer) multiLine(node *SyntaxNode, context layoutContext) bool {
+	switch {
+	case node.Kind == SyntaxDict && context == layoutRoot:
+		return true
+	case node.Kind == SyntaxDict && context == layoutEntry && len(node.Values()) > 0:
+		return true
+	}
+	return p.mustBreak(node)
+}
+
+// Check if node contains line breaks or comments, including in any of its
+// descendants. Results are cached as the check is repeated for every level.
+func (p *printer) mustBreak(node *SyntaxNode) bool {
+	if b, ok := p.breaks[node]; ok {
+		return b
+	}
+	b := false
+	for _, child := range node.Children {
+		switch {
+		case child.Kind == SyntaxComment:
+			b = true
+		case child.Kind == SyntaxSpace:
+			b = bytes.IndexByte(child.Raw, '\n') >= 0
+		case child.Kind == SyntaxDict || child.Kind == SyntaxList:
+			b = p.mustBreak(child)
+		}
+		if b {
+			break
+		}
+	}
+	p.breaks[node] = b
+	return b
+}
+
+// Write a value on a single line.
+func (p *printer) writeInline(node *SyntaxNode) {
+	if node.Kind == SyntaxString {
+		p.buf.Write(node.Raw)
+		return
+	}
+	p.buf.Write(node.Children[0].Raw)
+	p.buf.WriteByte(' ')
+	for _, child := range node.Values() {
+		if child.Kind == SyntaxKey {
+			p.buf.Write(child.Raw)
+		} else {
+			p.writeInline(child)
+		}
+		p.buf.WriteByte(' ')
+	}
+	p.buf.Write(node.Children[len(node.Children)-1].Raw)
+}
+
+// Print the lines of the body of a Root, Dict or List node at the specified
+// indentation level. Each line is preceded by a new-line except for the first
+// line of the root level.
+func (p *printer) printBody(node *SyntaxNode, level int) error {
+	children := node.Children
+	if node.Kind != SyntaxRoot {
+		children = children[1 : len(children)-1]
+	}
+	context := layoutItem
+	switch node.Kind {
+	case SyntaxRoot:
+		context = layoutRoot
+	case SyntaxDict:
+		context = layoutEntry
+	}
+
+	open, lines := p.layoutLines(children, context)
+	if open != nil {
+		p.buf.WriteByte(' ')
+		p.buf.Write(open.Raw)
+	}
+
+	// Print lines in blocks separated by blank lines, comment lines and lines
+	// with values broken over multiple lines. Keys and comments are aligned
+	// within blocks.
+	for start := 0; start < len(lines); {
+		end := start + 1
+		if p.singleLineLayout(&lines[start], context) {
+			for end < len(lines) && !lines[end].blank && p.singleLineLayout(&lines[end], context) {
+				end++
+			}
+		}
+		if err := p.printBlock(lines[start:end], level, context, node.Kind == SyntaxRoot && start == 0); err != nil {
+			return err
+		}
+		start = end
+	}
+	return nil
+}
+
+// Check if a line of a body is printed on a single line and is not a comment
+// line.
+func (p *printer) singleLineLayout(line *layoutLine, context layoutContext) bool {
+	if len(line.values) == 0 {
+		return false
+	}
+	for _, value := range line.values {
+		if p.multiLine(value, context) {
+			return false
+		}
+	}
+	return true
+}
+
+// Print a block of lines. First is set for the first line of the root level.
+func (p *printer) printBlock(lines []layoutLine, level int, context layoutContext, first bool) error {
+	column := level * p.indentWidth()
+	keyWidth := minKeyWidth
+	for _, line := range lines {
+		if line.key != nil {
+			keyWidth = max(keyWidth, column+utf8.RuneCount(line.key.Raw)+1)
+		}
+	}
+
+	// Lines are formatted before being written to align comments.
+	texts := make([]string, len(lines))
+	commentWidth := 0
+	for i, line := range lines {
+		lp := printer{Printer: p.Printer, breaks: p.breaks}
+		if line.key != nil {
+			lp.buf.Write(line.key.Raw)
+			pad := 1
+			if p.AlignKeys {
+				pad = keyWidth - column - utf8.RuneCount(line.key.Raw)
+			}
+			lp.buf.WriteString(strings.Repeat(" ", pad))
+		}
+		for j, value := range line.values {
+			if j > 0 {
+				lp.buf.WriteByte(' ')
+			}
+			if err := lp.printSyntaxValue(value, level, context); err != nil {
+				return err
+			}
+		}
+		texts[i] = lp.buf.String()
+		if line.comment != nil && len(line.values) > 0 {
+			commentWidth = max(commentWidth, column+utf8.RuneCountInString(texts[i])+1)
+		}
+	}
+
+	for i, line := range lines {
+		if !first || i > 0 {
+			p.buf.WriteByte('\n')
+			if line.blank {
+				p.buf.WriteByte('\n')
+			}
+		}
+		p.indent(level)
+		p.buf.WriteString(texts[i])
+		if line.comment != nil {
+			if len(line.values) > 0 {
+				pad := 1
+				if p.AlignKeys && len(lines) > 1 {
+					pad = commentWidth - column - utf8.RuneCountInString(texts[i])
+				}
+				p.buf.WriteString(strings.Repeat(" ", pad))
+			}
+			p.buf.Write(line.comment.Raw)
+		}
+	}
+	return nil
+}
+
+// Split the children of a Root, Dict or List node between its delimiters into
+// lines. Context is the context of the values of the node. Returns any comment
+// following the opening delimiter on the same line and the lines.
+func (p *printer) layoutLines(children []*SyntaxNode, context layoutContext) (*SyntaxNode, []layoutLine) {
+	var open *SyntaxNode
+	var lines []layoutLine
+	newLines := 0 // Number of new-lines since the previous token.
+	start := true // No tokens seen yet.
+
+	for _, child := range children {
+		switch {
+		case child.Kind == SyntaxSpace:
+			newLines += bytes.Count(child.Raw, []byte("\n"))
+			continue
+		case child.Kind == SyntaxComment:
+			n := len(lines)
+			switch {
+			case newLines == 0 && start && context != layoutRoot:
+				open = child
+			case newLines == 0 && n > 0 && lines[n-1].comment == nil && len(lines[n-1].values) > 0:
+				lines[n-1].comment = child
+			case n > 0 && lines[n-1].key != nil && len(lines[n-1].values) == 0:
+				// Comment between a key and its value, moved before the entry.
+				lines = append(lines[:n-1], layoutLine{comment: child, blank: lines[n-1].blank}, lines[n-1])
+				lines[n].blank = false
+			default:
+				lines = append(lines, layoutLine{comment: child, blank: newLines > 1 && n > 0})
+			}
+		case child.Kind == SyntaxKey:
+			lines = append(lines, layoutLine{key: child, blank: newLines > 1 && len(lines) > 0})
+		case child.IsValue():
+			n := len(lines)
+			switch {
+			case context == layoutEntry && n > 0 && lines[n-1].key != nil && len(lines[n-1].values) == 0:
+				lines[n-1].values = []*SyntaxNode{child}
+			case context != layoutEntry && newLines == 0 && n > 0 && len(lines[n-1].values) > 0 && lines[n-1].comment == nil &&
+				!p.multiLine(child, context) && !p.multiLine(lines[n-1].values[len(lines[n-1].values)-1], context):
+				lines[n-1].values = append(lines[n-1].values, child)
+			default:
+				lines = append(lines, layoutLine{values: []*SyntaxNode{child}, blank: newLines > 1 && n > 0})
+			}
+		}
+		newLines = 0
+		start = false
+	}
+	return open, lines
+}
@@ -49,14 +49,129 @@ func ExamplePrettyPrint() {
 	// }
 }
 
-// Exercise some functions not possible to run through examples.
-func Test_PrintCoverage(t *testing.T) {
-	es := new(errorSink)
-	buf := newPrintBuf(es)
-	buf.write([]byte("test"))
-	buf.flush()
-	es.e = fmt.Errorf("test")
-	if bytes := buf.bytes(); bytes != nil {
-		t.Errorf("printBuf.bytes() = %v want nil", bytes)
+func ExamplePrinter() {
+	printer := Printer{Indent: 2, MaxWidth: 30, FinalNewline: true}
+	buf, err := printer.Print([]byte(`{ name: example ports: [ 80 443 ] servers: [ { host: a.example.com } { host: b.example.com } ] }`))
+	if err != nil {
+		fmt.Println(err)
+	}
+	fmt.Print(string(buf))
+	// Output:
+	// {
+	//   name: example
+	//   ports: [ 80 443 ]
+	//   servers: [
+	//     { host: a.example.com }
+	//     { host: b.example.com }
+	//   ]
+	// }
+}
+
+func TestPrinter(t *testing.T) {
+	pot := "{ a: b long-key: { c: d } e: [ f g ] } [ h ] i"
+	tests := []struct {
+		printer Printer
+		out     string
+	}{
+		{Printer{}, "{\n    a: b\n    long-key: {\n        c: d\n    }\n    e: [ f g ]\n}\n[ h ]\ni"},
+		{Printer{AlignKeys: true, IndentTabs: true}, "{\n\ta: b\n\tlong-key: {\n\t\tc: d\n\t}\n\te: [ f g ]\n}\n[ h ]\ni"},
+		{Printer{Compact: true, FinalNewline: true}, "{ a: b long-key: { c: d } e: [ f g ] }\n[ h ]\ni\n"},
+		{Printer{MaxWidth: 40}, "{ a: b long-key: { c: d } e: [ f g ] }\n[ h ]\ni"},
+		{Printer{MaxWidth: 22, AlignKeys: true}, "{\n    a:        b\n    long-key: { c: d }\n    e:        [ f g ]\n}\n[ h ]\ni"},
+		{Printer{MaxWidth: 11, Indent: 1}, "{\n a: b\n long-key: {\n  c: d\n }\n e: [ f g ]\n}\n[ h ]\ni"},
+		{Printer{MaxWidth: 4}, "{\n    a: b\n    long-key: {\n        c: d\n    }\n    e: [\n        f\n        g\n    ]\n}\n[\n    h\n]\ni"},
+	}
+	for _, test := range tests {
+		buf, err := test.printer.Print([]byte(pot))
+		if err != nil {
+			t.Errorf("%+v: %s", test.printer, err)
+		} else if string(buf) != test.out {
+			t.Errorf("%+v: output =\n%s\nwant\n%s", test.printer, buf, test.out)
+		}
+	}
+
+	if _, err := new(Printer).PrintNodes([]*Node{{Kind: DictNode, Entries: []Entry{{Key: "a b", Value: NewString("c")}}}}); err == nil {
+		t.Errorf("PrintNodes() with invalid key succeeded")
+	}
+	nodes, err := parseNodes(NewParserWithOptions([]byte("{ _id: 1 smörgås: 2 }"), &ParserOptions{ExtendedKeys: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf, err := (&Printer{Compact: true}).PrintNodes(nodes); err != nil || string(buf) != "{ _id: 1 smörgås: 2 }" {
+		t.Errorf("PrintNodes() with extended keys = (%s, %v)", buf, err)
+	}
+	if _, err := new(Printer).PrintNodes([]*Node{NewList(nil)}); err == nil {
+		t.Errorf("PrintNodes() with nil node succeeded")
+	}
+}
+
+func ExamplePrinter_keepLayout() {
+	printer := Printer{AlignKeys: true, KeepLayout: true}
+	buf, err := printer.Print([]byte(`# Service configuration.
+{
+  name: frontend   # Service name.
+  version:   1.2.3
+
+  # Ports to listen on.
+  ports: [ 80
+    443 ]
+}`))
+	if err != nil {
+		fmt.Println(err)
+	}
+	fmt.Println(string(buf))
+	// Output:
+	// # Service configuration.
+	// {
+	//     name:    frontend # Service name.
+	//     version: 1.2.3
+	//
+	//     # Ports to listen on.
+	//     ports: [
+	//         80
+	//         443
+	//     ]
+	// }
+}
+
+func TestPrinter_KeepLayout(t *testing.T) {
+	tests := []struct {
+		in, out string
+	}{
+		{"", ""},
+		{"a b\n\n\nc # c\n# d", "a b\n\nc # c\n# d"},
+		{"{ a: b c: [ d e ] f: {} }", "{\n\ta: b\n\tc: [ d e ]\n\tf: { }\n}"},
+		{"{ a: # c\n b }", "{\n\t# c\n\ta: b\n}"},
+		{"[ { a: b } { c: d\n} ]", "[\n\t{ a: b }\n\t{\n\t\tc: d\n\t}\n]"},
+		{"{ # c\n}", "{ # c\n}"},
+		{"{ aa: b # c\n a: bbb # d\n}", "{\n\taa: b # c\n\ta: bbb # d\n}"},
+	}
+	printer := Printer{IndentTabs: true, KeepLayout: true}
+	for _, test := range tests {
+		buf, err := printer.Print([]byte(test.in))
+		if err != nil {
+			t.Errorf("%q: %s", test.in, err)
+		} else if string(buf) != test.out {
+			t.Errorf("%q: output = %q want %q", test.in, buf, test.out)
+		}
+	}
+
+	// Formatting must not change values and formatting again must not change
+	// the output.
+	printer = Printer{AlignKeys: true, KeepLayout: true, FinalNewline: true}
+	for _, input := range []string{exampleSyntax1, examplePrint1, example_parserComment1, exampleReaderParser} {
+		buf, err := printer.Print([]byte(input))
+		if err != nil {
+			t.Errorf("%q: %s", input, err)
+			continue
+		}
+		want, _ := Parse([]byte(input))
+		got, err := Parse(buf)
+		if err != nil || fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%q: values changed to %q", input, buf)
+		}
+		if again, err := printer.Print(buf); err != nil || string(again) != string(buf) {
+			t.Errorf("%q: formatting again changed %q to %q", input, buf, again)
+		}
 	}
 }
@@ -0,0 +1,410 @@
+package pot
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Kind of syntax tree node.
+type SyntaxKind int
+
+const (
+	SyntaxRoot      SyntaxKind = iota // Root of a syntax tree holding root level values.
+	SyntaxDict                        // Dictionary including its braces.
+	SyntaxList                        // List including its brackets.
+	SyntaxKey                         // Dictionary key including its ':'.
+	SyntaxString                      // String with quotes and escape codes intact.
+	SyntaxDelimiter                   // One of '{', '}', '[' or ']'.
+	SyntaxSpace                       // Space.
+	SyntaxComment                     // Comment excluding the new-line ending it.
+)
+
+// Concrete syntax tree node.
+//
+// The syntax tree records every token of the text input including the space
+// and comments between them. Writing out an unmodified tree reproduces the text
+// input byte for byte and modifying a node only changes the text of that node.
+//
+// Root, Dict and List nodes have children, all other nodes are leaves holding
+// raw text.
+type SyntaxNode struct {
+	Kind     SyntaxKind
+	Raw      []byte        // Raw text of leaf nodes.
+	Children []*SyntaxNode // Child nodes of Root, Dict and List nodes.
+	Location Location      // Start location in the text input, zero for nodes created by the application.
+}
+
+// Dictionary entry of a syntax tree.
+type SyntaxEntry struct {
+	Key   *SyntaxNode
+	Value *SyntaxNode
+}
+
+// Parse POT text into a concrete syntax tree.
+// Returns the root node or the first error.
+func ParseSyntax(pot []byte) (*SyntaxNode, error) {
+	p := &syntaxParser{stream: newTokenStream(newParserBuf(pot))}
+	return p.parse()
+}
+
+// Create a new string node holding value, quoted and escaped as needed.
+func NewSyntaxString(value string) *SyntaxNode {
+	return &SyntaxNode{Kind: SyntaxString, Raw: []byte(formatString([]byte(value)))}
+}
+
+// Get the text of the node and all its children.
+func (node *SyntaxNode) Bytes() []byte {
+	var buf bytes.Buffer
+	node.write(&buf)
+	return buf.Bytes()
+}
+
+// Write the text of the node and all its children to buf.
+func (node *SyntaxNode) write(buf *bytes.Buffer) {
+	buf.Write(node.Raw)
+	for _, child := range node.Children {
+		child.write(buf)
+	}
+}
+
+// Check if the node is space or a comment.
+func (node *SyntaxNode) IsTrivia() bool {
+	return node.Kind == SyntaxSpace || node.Kind == SyntaxComment
+}
+
+// Check if the node is a Dict, List or String value.
+func (node *SyntaxNode) IsValue() bool {
+	return node.Kind == SyntaxDict || node.Kind == SyntaxList || node.Kind == SyntaxString
+}
+
+// Get the name of a Key node or the value of a String node with escape codes
+// and quotes evaluated. Returns an empty string for other nodes.
+func (node *SyntaxNode) Value() string {
+	lex := newLexer(newParserBuf(node.Raw))
+	switch tok, err := lex.Next(); {
+	case err != nil:
+	case node.Kind == SyntaxKey && tok.Kind == TokenKey, node.Kind == SyntaxString && tok.Kind == TokenString:
+		return string(tok.Value)
+	}
+	return ""
+}
+
+// Set the value of a String node, quoting and escaping it as needed.
+func (node *SyntaxNode) SetString(value string) {
+	node.Raw = []byte(formatString([]byte(value)))
+}
+
+// Get the values of a Root, List or Dict node.
+// For dictionaries this includes both keys and values.
+func (node *SyntaxNode) Values() []*SyntaxNode {
+	var values []*SyntaxNode
+	for _, child := range node.Children {
+		if child.IsValue() || child.Kind == SyntaxKey {
+			values = append(values, child)
+		}
+	}
+	return values
+}
+
+// Get the entries of a Dict node in order.
+func (node *SyntaxNode) Entries() []SyntaxEntry {
+	var entries []SyntaxEntry
+	var key *SyntaxNode
+	for _, child := range node.Children {
+		switch {
+		case child.Kind == SyntaxKey:
+			key = child
+		case child.IsValue() && key != nil:
+			entries = append(entries, SyntaxEntry{key, child})
+			key = nil
+		}
+	}
+	return entries
+}
+
+// Get the value of the first entry with the specified key of a Dict node or
+// nil if there is no such entry.
+func (node *SyntaxNode) Lookup(key string) *SyntaxNode {
+	for _, entry := range node.Entries() {
+		if entry.Key.Value() == key {
+			return entry.Value
+		}
+	}
+	return nil
+}
+
+// Append an entry to a Dict node.
+// The space surrounding the last entry is copied to make the new entry blend
+// in with the existing layout.
+func (node *SyntaxNode) AppendEntry(key string, value *SyntaxNode) error {
+	if node.Kind != SyntaxDict {
+		return fmt.Errorf("append entry to %s node", node.Kind)
+	}
+	if !validKey([]byte(key), false) {
+		return fmt.Errorf("invalid dictionary key %q", key)
+	}
+
+	lead, sep := []byte(" "), []byte(" ")
+	if entries := node.Entries(); len(entries) > 0 {
+		last := entries[len(entries)-1]
+		i := node.childIndex(last.Key)
+		if space := node.Children[i-1]; space.Kind == SyntaxSpace {
+			lead = lastLine(space.Raw)
+		}
+		if space := node.Children[i+1]; space.Kind == SyntaxSpace {
+			sep = space.Raw
+		}
+	}
+	node.appendChildren(
+		&SyntaxNode{Kind: SyntaxSpace, Raw: dupBytes(lead)},
+		&SyntaxNode{Kind: SyntaxKey, Raw: []byte(key + ":")},
+		&SyntaxNode{Kind: SyntaxSpace, Raw: dupBytes(sep)},
+		value)
+	return nil
+}
+
+// Insert children at the index given by appendIndex, the first child being the
+// space preceding the others. Space is added before the closing delimiter of
+// previously empty nodes. A new line is started after a comment ending the line
+// of the last entry or value, as the children would otherwise become part of
+// the comment.
+func (node *SyntaxNode) appendChildren(children ...*SyntaxNode) {
+	i := node.appendIndex()
+	if i == 1 && node.Children[i].Kind == SyntaxDelimiter {
+		children = append(children, &SyntaxNode{Kind: SyntaxSpace, Raw: []byte(" ")})
+	}
+	if node.Children[i-1].Kind == SyntaxComment && bytes.IndexByte(children[0].Raw, '\n') < 0 {
+		children[0].Raw = append([]byte("\n"), node.lineIndent(i-1)...)
+	}
+	node.insertChildren(i, children...)
+}
+
+// Get the indentation of the line holding the child at index i or an empty
+// slice if the line starts before the node.
+func (node *SyntaxNode) lineIndent(i int) []byte {
+	for ; i >= 0; i-- {
+		child := node.Children[i]
+		if j := bytes.LastIndexByte(child.Raw, '\n'); j >= 0 && child.Kind == SyntaxSpace {
+			return dupBytes(child.Raw[j+1:])
+		}
+	}
+	return nil
+}
+
+// Append a value to a List node.
+// The space preceding the last value is copied to make the new value blend in
+// with the existing layout.
+func (node *SyntaxNode) AppendValue(value *SyntaxNode) error {
+	if node.Kind != SyntaxList {
+		return fmt.Errorf("append value to %s node", node.Kind)
+	}
+	lead := []byte(" ")
+	if values := node.Values(); len(values) > 0 {
+		i := node.childIndex(values[len(values)-1])
+		if space := node.Children[i-1]; space.Kind == SyntaxSpace {
+			lead = lastLine(space.Raw)
+		}
+	}
+	node.appendChildren(&SyntaxNode{Kind: SyntaxSpace, Raw: dupBytes(lead)}, value)
+	return nil
+}
+
+// Remove the first entry with the specified key from a Dict node together with
+// the space preceding it. Returns false if there is no such entry.
+func (node *SyntaxNode) RemoveEntry(key string) bool {
+	for _, entry := range node.Entries() {
+		if entry.Key.Value() == key {
+			node.removeChildren(node.childIndex(entry.Key), node.childIndex(entry.Value))
+			return true
+		}
+	}
+	return false
+}
+
+// Remove a value from a List node together with the space preceding it.
+// Returns false if the value is not a child of the node.
+func (node *SyntaxNode) RemoveValue(value *SyntaxNode) bool {
+	i := node.childIndex(value)
+	if i < 0 || node.Kind != SyntaxList {
+		return false
+	}
+	node.removeChildren(i, i)
+	return true
+}
+
+// Get the index of child or -1 if it's not a child of node.
+func (node *SyntaxNode) childIndex(child *SyntaxNode) int {
+	for i, c := range node.Children {
+		if c == child {
+			return i
+		}
+	}
+	return -1
+}
+
+// Get the index where new entries or values should be inserted in a Dict or
+// List node. This is after the last value and any comment on the same line or
+// directly after the opening delimiter if there are no values.
+func (node *SyntaxNode) appendIndex() int {
+	i := 0
+	for j, child := range node.Children {
+		if child.IsValue() {
+			i = j + 1
+		}
+	}
+	if i == 0 {
+		return 1
+	}
+	for j := i; j < len(node.Children); j++ {
+		child := node.Children[j]
+		if child.Kind == SyntaxComment {
+			return j + 1
+		}
+		if child.Kind != SyntaxSpace || bytes.IndexByte(child.Raw, '\n') >= 0 {
+			break
+		}
+	}
+	return i
+}
+
+// Insert children at index i.
+func (node *SyntaxNode) insertChildren(i int, children ...*SyntaxNode) {
+	node.Children = append(node.Children[:i], append(children, node.Children[i:]...)...)
+}
+
+// Remove children with index from i to j inclusive, any space preceding them
+// and any comment following them on the same line.
+func (node *SyntaxNode) removeChildren(i, j int) {
+	if i > 0 && node.Children[i-1].Kind == SyntaxSpace {
+		i--
+	}
+	for k := j + 1; k < len(node.Children); k++ {
+		child := node.Children[k]
+		if child.Kind == SyntaxComment {
+			j = k
+			break
+		}
+		if child.Kind != SyntaxSpace || bytes.IndexByte(child.Raw, '\n') >= 0 {
+			break
+		}
+	}
+	node.Children = append(node.Children[:i], node.Children[j+1:]...)
+}
+
+// Implements fmt.Stringer.
+func (kind SyntaxKind) String() string {
+	switch kind {
+	case SyntaxRoot:
+		return "root"
+	case SyntaxDict:
+		return "dictionary"
+	case SyntaxList:
+		return "list"
+	case SyntaxKey:
+		return "dictionary-key"
+	case SyntaxString:
+		return "string"
+	case SyntaxDelimiter:
+		return "delimiter"
+	case SyntaxSpace:
+		return "space"
+	case SyntaxComment:
+		return "comment"
+	}
+	return "unknown"
+}
+
+// Get space from the last new-line onwards, dropping any blank lines.
+func lastLine(space []byte) []byte {
+	if i := bytes.LastIndexByte(space, '\n'); i >= 0 {
+		return space[i:]
+	}
+	return space
+}
+
+// Duplicate a byte slice.
+func dupBytes(b []byte) []byte {
+	return append([]byte(nil), b...)
+}
+
+// Parser building a syntax tree from the token stream of the parsers.
+//
+// The tree is built using the grammar of the parsers, which makes it report the
+// same errors. Open dictionaries and lists are kept on a stack instead of
+// recursing. Space and comments are lexed from the text between the tokens of
+// the stream.
+type syntaxParser struct {
+	stream   *tokenStream
+	offset   int      // Byte offset of the text input not added to the tree yet.
+	location Location // Location of the text input not added to the tree yet.
+}
+
+// Parse the token stream into a Root node.
+func (p *syntaxParser) parse() (*SyntaxNode, error) {
+	root := &SyntaxNode{Kind: SyntaxRoot}
+	blocks := []blockPos{{streamPos: streamPos{stream: p.stream, end: -1}, open: TokenEOF}}
+	nodes := []*SyntaxNode{root}
+	for len(blocks) > 0 {
+		block, node := &blocks[len(blocks)-1], nodes[len(nodes)-1]
+		i, err := block.nextIndex()
+		switch {
+		case err != nil:
+			return nil, err
+		case i < 0:
+			if block.open == TokenEOF {
+				p.addSpace(node, block.endIndex())
+			} else {
+				p.addToken(node, SyntaxDelimiter, block.end)
+			}
+			blocks, nodes = blocks[:len(blocks)-1], nodes[:len(nodes)-1]
+			continue
+		}
+
+		switch kind := p.stream.kind(i); kind {
+		case TokenBraceOpen, TokenBracketOpen:
+			child := &SyntaxNode{Kind: SyntaxDict, Location: p.stream.at(i).location}
+			if kind == TokenBracketOpen {
+				child.Kind = SyntaxList
+			}
+			p.addSpace(node, i)
+			node.Children = append(node.Children, child)
+			p.addToken(child, SyntaxDelimiter, i)
+			blocks, nodes = append(blocks, block.block(i)), append(nodes, child)
+		case TokenKey:
+			p.addToken(node, SyntaxKey, i)
+		default:
+			p.addToken(node, SyntaxString, i)
+		}
+	}
+	return root, nil
+}
+
+// Add the space and comments preceding token i and then the token to node.
+func (p *syntaxParser) addToken(node *SyntaxNode, kind SyntaxKind, i int) {
+	p.addSpace(node, i)
+	tok := p.stream.token(i)
+	node.Children = append(node.Children, newSyntaxLeaf(kind, tok))
+	p.offset, p.location = tok.Offset+len(tok.Raw), tok.byteLocation(len(tok.Raw))
+}
+
+// Add the space and comments preceding token i to node.
+func (p *syntaxParser) addSpace(node *SyntaxNode, i int) {
+	input, end := &p.stream.input, p.stream.at(i).offset
+	if end == p.offset {
+		return
+	}
+	lex := newLexer(&parserBuf{bytes: input.bytes[p.offset-input.offset : end-input.offset], location: p.location, offset: p.offset})
+	for tok, _ := lex.Next(); tok.Kind != TokenEOF; tok, _ = lex.Next() {
+		kind := SyntaxSpace
+		if tok.Kind == TokenComment {
+			kind = SyntaxComment
+		}
+		node.Children = append(node.Children, newSyntaxLeaf(kind, tok))
+	}
+	p.offset, p.location = end, p.stream.at(i).location
+}
+
+// Create a leaf node from a token.
+func newSyntaxLeaf(kind SyntaxKind, tok Token) *SyntaxNode {
+	return &SyntaxNode{Kind: kind, Raw: tok.Raw, Location: tok.Location}
+}
@@ -0,0 +1,154 @@
+package pot
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+var exampleSyntax1 = `# Service configuration.
+{
+    name:    frontend   # Service name.
+    version: 1.2.3
+
+    ports:   [ 80
+               443 ]
+}
+`
+
+func ExampleParseSyntax() {
+	root, err := ParseSyntax([]byte(exampleSyntax1))
+	if err != nil {
+		fmt.Printf("error: %s\n", err)
+		return
+	}
+	dict := root.Values()[0]
+	dict.Lookup("version").SetString("1.2.4")
+	dict.Lookup("ports").AppendValue(NewSyntaxString("8080"))
+	dict.AppendEntry("owner", NewSyntaxString("web team"))
+	dict.RemoveEntry("name")
+	fmt.Printf("%s", root.Bytes())
+	// Output:
+	// # Service configuration.
+	// {
+	//     version: 1.2.4
+	//
+	//     ports:   [ 80
+	//                443
+	//                8080 ]
+	//     owner:   "web team"
+	// }
+}
+
+// Test that unmodified syntax trees reproduce the text input.
+func TestParseSyntax_RoundTrip(t *testing.T) {
+	inputs := []string{
+		"",
+		" \n",
+		exampleSyntax1,
+		examplePrint1,
+		example_ParserDict3 + "\r",
+		example_parserComment1,
+		exampleReaderParser,
+		"{}[]\"\"",
+		"{ a: { b: [ c ] } }# comment",
+	}
+	for _, input := range inputs {
+		root, err := ParseSyntax([]byte(input))
+		if err != nil {
+			t.Errorf("ParseSyntax(%q) = %v", input, err)
+		} else if output := string(root.Bytes()); output != input {
+			t.Errorf("ParseSyntax(%q).Bytes() = %q", input, output)
+		}
+	}
+}
+
+// Test that errors are the same as reported by the parsers.
+func TestParseSyntax_Errors(t *testing.T) {
+	inputs := []string{
+		"{ -invalid-key: 0 }",
+		"{ : foo }",
+		"{ foo: }",
+		"{ foo bar: baz }",
+		"{ unterminated-key}",
+		"{ [ a ] }",
+		"{ a\\: b }",
+		"[ unterminated\\ block",
+		"{ unterminated: block",
+		"\"unterminated\\ quote",
+		"invalid-escape-code-\\m",
+		"unterminated-escape-code-\\",
+		"]",
+		"[ } ]",
+		"\nunescaped-or-unquoted-colon-in-string:",
+		"{ a: [ b } ]",
+		"{ a: [ x: y } ]",
+		"[ { a: b ] }",
+		strings.Repeat("[", DefaultMaxDepth+1) + strings.Repeat("]", DefaultMaxDepth+1),
+	}
+	for _, input := range inputs {
+		want := testDescribeParsers(ioutil.Discard, NewParser([]byte(input)))
+		if _, err := ParseSyntax([]byte(input)); fmt.Sprint(err) != fmt.Sprint(want) {
+			t.Errorf("ParseSyntax(%q) = %v want %v", input, err, want)
+		}
+	}
+}
+
+func TestSyntaxNode_Edit(t *testing.T) {
+	tests := []struct {
+		input  string
+		edit   func(node *SyntaxNode) error
+		output string
+	}{
+		{"{}", func(node *SyntaxNode) error { return node.AppendEntry("a", NewSyntaxString("b")) }, "{ a: b }"},
+		{"{ }", func(node *SyntaxNode) error { return node.AppendEntry("a", NewSyntaxString("b")) }, "{ a: b }"},
+		{"{ a: b }", func(node *SyntaxNode) error { return node.AppendEntry("c", NewSyntaxString("d")) }, "{ a: b c: d }"},
+		{"[]", func(node *SyntaxNode) error { return node.AppendValue(NewSyntaxString("a")) }, "[ a ]"},
+		{"[ a\n  b ]", func(node *SyntaxNode) error { return node.AppendValue(NewSyntaxString("c d")) }, "[ a\n  b\n  \"c d\" ]"},
+		{"{ a: b # note\n}", func(node *SyntaxNode) error { return node.AppendEntry("c", NewSyntaxString("d")) }, "{ a: b # note\nc: d\n}"},
+		{"{\n    a: b # note\n}", func(node *SyntaxNode) error { return node.AppendEntry("c", NewSyntaxString("d")) }, "{\n    a: b # note\n    c: d\n}"},
+		{"[ a # c\n]", func(node *SyntaxNode) error { return node.AppendValue(NewSyntaxString("d")) }, "[ a # c\nd\n]"},
+		{"[\n  a b # c\n]", func(node *SyntaxNode) error { return node.AppendValue(NewSyntaxString("d")) }, "[\n  a b # c\n  d\n]"},
+		{"[ a b c ]", func(node *SyntaxNode) error {
+			if !node.RemoveValue(node.Values()[1]) {
+				return fmt.Errorf("RemoveValue() = false")
+			}
+			return nil
+		}, "[ a c ]"},
+		{"{ a: b a: c }", func(node *SyntaxNode) error {
+			if !node.RemoveEntry("a") || node.RemoveEntry("b") {
+				return fmt.Errorf("unexpected RemoveEntry() result")
+			}
+			return nil
+		}, "{ a: c }"},
+		{"{ a: \"b c\" }", func(node *SyntaxNode) error {
+			if value := node.Lookup("a").Value(); value != "b c" {
+				return fmt.Errorf("Value() = %q", value)
+			}
+			return nil
+		}, "{ a: \"b c\" }"},
+	}
+	for _, test := range tests {
+		root, err := ParseSyntax([]byte(test.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = test.edit(root.Values()[0]); err != nil {
+			t.Errorf("%q: %v", test.input, err)
+		} else if output := string(root.Bytes()); output != test.output {
+			t.Errorf("%q: output %q want %q", test.input, output, test.output)
+		}
+	}
+
+	root, _ := ParseSyntax([]byte("[ a ] { }"))
+	if root.Values()[0].AppendEntry("a", NewSyntaxString("b")) == nil {
+		t.Errorf("AppendEntry() on list succeeded")
+	}
+	if root.Values()[1].AppendValue(NewSyntaxString("b")) == nil {
+		t.Errorf("AppendValue() on dictionary succeeded")
+	}
+	if root.Values()[1].AppendEntry("-a", NewSyntaxString("b")) == nil {
+		t.Errorf("AppendEntry() with invalid key succeeded")
+	}
+}
@@ -0,0 +1,429 @@
+package pot
+
+// Tokens of a text input shared by the parsers operating on it.
+//
+// Tokens are lexed once on demand and kept so that nested parsers iterate over
+// them without scanning the text again. Blocks are matched while lexing, which
+// makes finding the end of a dictionary or list a lookup. Braces and brackets
+// are matched independently of each other.
+type tokenStream struct {
+	input    parserBuf       // Text input.
+	lex      *Lexer          // Lexer operating on the remaining text input.
+	chunks   [][]streamToken // Tokens lexed so far, excluding space and comments.
+	n        int             // Number of tokens lexed so far.
+	values   map[int][]byte  // String values that differ from the token text by token index.
+	errs     map[int]error   // Errors detected by the Lexer by token index.
+	braces   []int           // Indices of unmatched '{' tokens.
+	brackets []int           // Indices of unmatched '[' tokens.
+}
+
+// Number of tokens per chunk of a token stream.
+// Tokens are stored in chunks to avoid copying them as the stream grows.
+const tokenChunkSize = 1024
+
+// Compact token of a token stream.
+// The token text is kept in the text input of the stream. Stream tokens hold no
+// pointers to not burden the garbage collector.
+type streamToken struct {
+	kind     TokenKind
+	location Location
+	offset   int  // Token start byte offset in the text input.
+	size     int  // Size of the token text.
+	match    int  // Index of the matching close token of open tokens, -1 if unmatched or 0 if not known yet.
+	value    bool // The string value differs from the token text and is kept in the values map.
+}
+
+// Create a new token stream operating on the supplied parser buffer.
+func newTokenStream(buf *parserBuf) *tokenStream {
+	return &tokenStream{input: *buf, lex: newLexer(buf)}
+}
+
+// Get the parser options of the stream.
+func (s *tokenStream) opts() *ParserOptions {
+	return s.input.opts()
+}
+
+// Get the index of token i or of the end of input token if there are fewer
+// tokens. Tokens are lexed as needed.
+func (s *tokenStream) index(i int) int {
+	for i >= s.n && !s.eof() {
+		s.lexNext()
+	}
+	if i >= s.n {
+		i = s.n - 1
+	}
+	return i
+}
+
+// Get the kind of token i.
+func (s *tokenStream) kind(i int) TokenKind {
+	return s.at(s.index(i)).kind
+}
+
+// Get token i.
+func (s *tokenStream) token(i int) Token {
+	i = s.index(i)
+	t := s.at(i)
+	tok := Token{
+		Kind:     t.kind,
+		Raw:      s.bytes(i, i),
+		Location: t.location,
+		Offset:   t.offset,
+	}
+	switch {
+	case t.value:
+		tok.Value = s.values[i]
+	case t.kind == TokenKey:
+		tok.Value = tok.Raw[:t.size-1]
+	case t.kind == TokenString:
+		tok.Value = tok.Raw
+	}
+	return tok
+}
+
+// Get previously lexed token i.
+func (s *tokenStream) at(i int) *streamToken {
+	return &s.chunks[i/tokenChunkSize][i%tokenChunkSize]
+}
+
+// Get the lexer error of token i or nil.
+func (s *tokenStream) err(i int) error {
+	return s.errs[i]
+}
+
+// Get the index of the token matching open token i or -1 if it's unmatched.
+func (s *tokenStream) match(i int) int {
+	t := s.at(i)
+	for t.match == 0 {
+		s.lexNext()
+	}
+	return t.match
+}
+
+// Check if all text input has been lexed.
+func (s *tokenStream) eof() bool {
+	return s.n > 0 && s.at(s.n-1).kind == TokenEOF
+}
+
+// Lex the next token that is not space or a comment.
+func (s *tokenStream) lexNext() {
+	tok, err := s.lex.Next()
+	for tok.Kind == TokenWhitespace || tok.Kind == TokenComment {
+		tok, err = s.lex.Next()
+	}
+
+	i := s.n
+	value := false
+	if err != nil {
+		if s.errs == nil {
+			s.errs = make(map[int]error)
+		}
+		s.errs[i] = err
+	}
+	switch tok.Kind {
+	case TokenBraceOpen:
+		s.braces = append(s.braces, i)
+	case TokenBracketOpen:
+		s.brackets = append(s.brackets, i)
+	case TokenBraceClose:
+		s.braces = s.matchClose(s.braces, i)
+	case TokenBracketClose:
+		s.brackets = s.matchClose(s.brackets, i)
+	case TokenString:
+		if value = len(tok.Value) != len(tok.Raw) || len(tok.Value) > 0 && &tok.Value[0] != &tok.Raw[0]; value {
+			if s.values == nil {
+				s.values = make(map[int][]byte)
+			}
+			s.values[i] = tok.Value
+		}
+	case TokenEOF:
+		for _, j := range s.braces {
+			s.at(j).match = -1
+		}
+		for _, j := range s.brackets {
+			s.at(j).match = -1
+		}
+		s.braces, s.brackets = nil, nil
+	}
+	if i%tokenChunkSize == 0 {
+		s.chunks = append(s.chunks, make([]streamToken, 0, tokenChunkSize))
+	}
+	chunk := &s.chunks[len(s.chunks)-1]
+	*chunk = append(*chunk, streamToken{kind: tok.Kind, location: tok.Location, offset: tok.Offset, size: len(tok.Raw), value: value})
+	s.n++
+}
+
+// Match close token i with the innermost unmatched open token of stack.
+// Returns the updated stack.
+func (s *tokenStream) matchClose(stack []int, i int) []int {
+	if n := len(stack); n > 0 {
+		s.at(stack[n-1]).match = i
+		return stack[:n-1]
+	}
+	return stack
+}
+
+// Get the text input from the start of token i to the end of token j.
+func (s *tokenStream) bytes(i, j int) []byte {
+	first, last := s.at(i), s.at(j)
+	return s.input.bytes[first.offset-s.input.offset : last.offset+last.size-s.input.offset]
+}
+
+// Get a parser buffer holding the text input from the start of token i to the
+// end of token j.
+func (s *tokenStream) buf(i, j int) parserBuf {
+	first, last := s.at(i), s.token(j)
+	return parserBuf{
+		bytes:     s.bytes(i, j),
+		location:  first.location,
+		offset:    first.offset,
+		end:       last.byteLocation(len(last.Raw)),
+		endOffset: last.Offset + len(last.Raw),
+		options:   s.input.options,
+	}
+}
+
+// Get a parser buffer holding the text input following token i.
+func (s *tokenStream) after(i int) *parserBuf {
+	tok := s.token(i)
+	end := tok.Offset + len(tok.Raw)
+	return &parserBuf{
+		bytes:    s.input.bytes[end-s.input.offset:],
+		location: tok.byteLocation(len(tok.Raw)),
+		offset:   end,
+		options:  s.input.options,
+	}
+}
+
+// Position of a parser in a token stream.
+// Embedded in block positions and used by ReaderParser for single values.
+type streamPos struct {
+	stream *tokenStream
+	pos    int // Index of the next token.
+	end    int // Index of the token closing the parser's block or -1 if the parser ends at the end of input.
+	depth  int // Number of blocks enclosing the parser's tokens.
+}
+
+// Position of a root, dictionary or list parser in a token stream.
+// Shared by the parsers and Walk, which steps through nested blocks using a
+// stack of block positions.
+type blockPos struct {
+	streamPos
+	open  TokenKind // Token opening the block or TokenEOF for the root level.
+	count int       // Number of keys and values returned.
+}
+
+// Create a block position for a dictionary or list parser operating on all
+// text input. A block spanning all text input is stripped.
+func newBlockPos(s *tokenStream, open TokenKind) blockPos {
+	p := blockPos{streamPos: streamPos{stream: s, end: -1, depth: 1}, open: open}
+	if s.kind(0) == open {
+		if m := s.match(0); m > 0 && s.kind(m+1) == TokenEOF {
+			p.pos, p.end = 1, m
+		}
+	}
+	return p
+}
+
+// Get the parser of the next key or value of the block, nil at the end of the
+// block or an error.
+func (b *blockPos) nextParser() (Parser, error) {
+	i, err := b.nextIndex()
+	if i < 0 {
+		return nil, err
+	}
+	return b.parser(i), nil
+}
+
+// Advance past the next key or value of the block and get the index of its
+// first token, -1 at the end of the block or an error. Keys and values of
+// dictionaries alternate.
+func (b *blockPos) nextIndex() (int, error) {
+	max := b.stream.opts().MaxEntries
+	switch {
+	case b.open == TokenBraceOpen && b.count%2 == 0:
+		i, err := b.nextKeyIndex()
+		if i < 0 {
+			return -1, err
+		}
+		if max > 0 && b.count/2 >= max {
+			return -1, b.stream.token(i).Location.errorf(ErrEntryLimit, "number of dictionary entries exceeds limit of %d", max)
+		}
+		b.count++
+		return i, nil
+	case b.open == TokenBraceOpen && b.atEnd():
+		return -1, b.stream.token(b.pos).Location.errorf(ErrMissingValue, "key without value in dictionary")
+	case b.open == TokenBracketOpen && max > 0 && b.count >= max && !b.atEnd():
+		return -1, b.stream.token(b.pos).Location.errorf(ErrEntryLimit, "number of list values exceeds limit of %d", max)
+	}
+	i, err := b.nextValueIndex()
+	if i >= 0 {
+		b.count++
+	}
+	return i, err
+}
+
+// Check if the parser has reached the end of its tokens.
+func (p *streamPos) atEnd() bool {
+	return p.pos == p.end || p.stream.kind(p.pos) == TokenEOF
+}
+
+// Get the index of the last token of the parser, which is either the close
+// token of its block or the end of input token.
+func (p *streamPos) endIndex() int {
+	if p.end >= 0 {
+		return p.end
+	}
+	for !p.stream.eof() {
+		p.stream.lexNext()
+	}
+	return p.stream.n - 1
+}
+
+// Get the next Dict, List or String parser, nil at the end of the parser's
+// tokens or an error.
+func (p *streamPos) nextValue() (Parser, error) {
+	i, err := p.nextValueIndex()
+	if i < 0 {
+		return nil, err
+	}
+	return p.parser(i), nil
+}
+
+// Advance past the next dictionary, list or string value and get the index of
+// its first token, -1 at the end of the parser's tokens or an error.
+func (p *streamPos) nextValueIndex() (int, error) {
+	if p.atEnd() {
+		return -1, nil
+	}
+	i := p.pos
+	tok := p.stream.token(i)
+	switch tok.Kind {
+	case TokenBraceOpen, TokenBracketOpen:
+		if err := p.checkBlock(tok); err != nil {
+			return -1, err
+		}
+		p.pos = p.stream.match(i) + 1
+		return i, nil
+	case TokenString:
+		if err := p.checkStringLength(tok, len(tok.Raw)); err != nil {
+			return -1, err
+		}
+		if err := p.stream.err(i); err != nil {
+			return -1, err
+		}
+		p.pos++
+		return i, nil
+	case TokenKey:
+		return -1, tok.byteLocation(len(tok.Value)).errorf(ErrInvalidString, "invalid character ':' in string")
+	}
+	return -1, tok.Location.errorf(ErrInvalidString, "invalid character '%c' in string", tok.Raw[0])
+}
+
+// Check that the block opened by tok at the current position is within the
+// depth limit and ends within the parser's tokens.
+func (p *streamPos) checkBlock(tok Token) error {
+	if max := p.stream.input.maxDepth(); max > 0 && p.depth >= max {
+		return tok.Location.errorf(ErrDepthLimit, "nesting depth exceeds limit of %d", max)
+	}
+	if m := p.stream.match(p.pos); m < 0 || p.end >= 0 && m > p.end {
+		// Unterminated blocks are reported where the enclosing block ends.
+		p.pos = p.endIndex()
+		endChar := byte('}')
+		if tok.Kind == TokenBracketOpen {
+			endChar = ']'
+		}
+		return p.stream.token(p.pos).Location.errorf(ErrUnterminatedBlock,
+			"end of input while parsing '%c%c' block", tok.Raw[0], endChar)
+	}
+	return nil
+}
+
+// Advance past the next dictionary key and get the index of its token, -1 at
+// the end of the parser's tokens or an error.
+func (p *streamPos) nextKeyIndex() (int, error) {
+	if p.atEnd() {
+		return -1, nil
+	}
+	tok := p.stream.token(p.pos)
+	if tok.Kind != TokenKey || !p.stream.input.validKey(tok.Value) {
+		return -1, keyError(tok, p.stream.after(p.pos), p.pos+1 == p.end)
+	}
+	if err := p.checkStringLength(tok, len(tok.Value)); err != nil {
+		return -1, err
+	}
+	p.pos++
+	return p.pos - 1, nil
+}
+
+// Get the block position of the keys or values of the block opened by token i.
+func (p *streamPos) block(i int) blockPos {
+	return blockPos{
+		streamPos: streamPos{stream: p.stream, pos: i + 1, end: p.stream.match(i), depth: p.depth + 1},
+		open:      p.stream.kind(i),
+	}
+}
+
+// Get a Dict, DictKey, List or String parser for the key or value starting with
+// token i.
+func (p *streamPos) parser(i int) Parser {
+	tok := p.stream.token(i)
+	switch tok.Kind {
+	case TokenBraceOpen:
+		return &Dict{org: p.stream.buf(i, p.stream.match(i)), blockPos: p.block(i)}
+	case TokenBracketOpen:
+		return &List{org: p.stream.buf(i, p.stream.match(i)), blockPos: p.block(i)}
+	case TokenKey:
+		return (*DictKey)(p.tokenBuf(tok, len(tok.Value)))
+	}
+	return (*String)(p.tokenBuf(tok, len(tok.Raw)))
+}
+
+// Check that the first n bytes of tok are within the string length limit.
+func (p *streamPos) checkStringLength(tok Token, n int) error {
+	if max := p.stream.opts().MaxStringLength; max > 0 && n > max {
+		return tok.Location.errorf(ErrStringLimit, "string length exceeds limit of %d bytes", max)
+	}
+	return nil
+}
+
+// Create a parser buffer holding the value of tok spanning the first n bytes
+// of the token text.
+func (p *streamPos) tokenBuf(tok Token, n int) *parserBuf {
+	return &parserBuf{
+		bytes:     tok.Value,
+		location:  tok.Location,
+		offset:    tok.Offset,
+		end:       tok.byteLocation(n),
+		endOffset: tok.Offset + n,
+		options:   p.stream.input.options,
+	}
+}
+
+// Skip the token at the current position together with any block it opens.
+// Used to resynchronise parsers after errors.
+func (p *streamPos) skip() {
+	if p.atEnd() {
+		return
+	}
+	switch p.stream.kind(p.pos) {
+	case TokenBraceOpen, TokenBracketOpen:
+		if m := p.stream.match(p.pos); m >= 0 && (p.end < 0 || m < p.end) {
+			p.pos = m + 1
+		} else {
+			p.pos = p.endIndex()
+		}
+	default:
+		p.pos++
+	}
+}
+
+// Skip the token at the current position and everything following it up to
+// the next dictionary key.
+// Used to resynchronise parsers after errors.
+func (p *streamPos) skipToKey() {
+	p.skip()
+	for !p.atEnd() && p.stream.kind(p.pos) != TokenKey {
+		p.skip()
+	}
+}
@@ -0,0 +1,80 @@
+package pot
+
+// Handler receiving the events produced by Walk.
+//
+// Byte slices refer to the text input unless the value contains escape codes or
+// quotes. Returning an error from a method stops the walk and the error is
+// returned by Walk as is.
+type Handler interface {
+	StartDict(location Location) error            // Dictionary start '{'.
+	EndDict(location Location) error              // Dictionary end '}'.
+	StartList(location Location) error            // List start '['.
+	EndList(location Location) error              // List end ']'.
+	Key(key []byte, location Location) error      // Dictionary key without the ':' delimiter.
+	String(value []byte, location Location) error // String value with escape codes and quotes evaluated.
+}
+
+// Walk the supplied text calling handler for every dictionary, list, key and
+// string in document order.
+//
+// Walk steps through the token stream of the parsers using the same grammar,
+// which makes it report the same errors, but does not create any parsers or
+// nodes. Open dictionaries and lists are kept on a stack instead of recursing.
+// This makes Walk suitable for filtering or converting large documents. The
+// walk stops at the first parse error. Events for the text preceding the error
+// have already been delivered at that point.
+func Walk(pot []byte, handler Handler) error {
+	return WalkWithOptions(pot, nil, handler)
+}
+
+// Walk the supplied text using options, see Walk.
+// Default options are used if options is nil.
+func WalkWithOptions(pot []byte, options *ParserOptions, handler Handler) error {
+	buf := newParserBuf(pot)
+	buf.options = options
+	if err := buf.checkInputSize(); err != nil {
+		return err
+	}
+	stream := newTokenStream(buf)
+	blocks := []blockPos{{streamPos: streamPos{stream: stream, end: -1}, open: TokenEOF}}
+	for len(blocks) > 0 {
+		block := &blocks[len(blocks)-1]
+		i, err := block.nextIndex()
+		switch {
+		case err != nil:
+			return err
+		case i < 0:
+			err = walkEnd(handler, block)
+			blocks = blocks[:len(blocks)-1]
+		default:
+			tok := stream.token(i)
+			switch tok.Kind {
+			case TokenBraceOpen:
+				err = handler.StartDict(tok.Location)
+				blocks = append(blocks, block.block(i))
+			case TokenBracketOpen:
+				err = handler.StartList(tok.Location)
+				blocks = append(blocks, block.block(i))
+			case TokenKey:
+				err = handler.Key(tok.Value, tok.Location)
+			default:
+				err = handler.String(tok.Value, tok.Location)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Deliver the end event of a dictionary or list block to handler.
+func walkEnd(handler Handler, block *blockPos) error {
+	switch block.open {
+	case TokenBraceOpen:
+		return handler.EndDict(block.stream.token(block.end).Location)
+	case TokenBracketOpen:
+		return handler.EndList(block.stream.token(block.end).Location)
+	}
+	return nil
+}
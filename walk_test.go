@@ -0,0 +1,202 @@
+package pot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// Handler printing events, one per line.
+type printHandler struct {
+	b strings.Builder
+}
+
+func (h *printHandler) StartDict(location Location) error {
+	fmt.Fprintf(&h.b, "%s: start dictionary\n", location)
+	return nil
+}
+
+func (h *printHandler) EndDict(location Location) error {
+	fmt.Fprintf(&h.b, "%s: end dictionary\n", location)
+	return nil
+}
+
+func (h *printHandler) StartList(location Location) error {
+	fmt.Fprintf(&h.b, "%s: start list\n", location)
+	return nil
+}
+
+func (h *printHandler) EndList(location Location) error {
+	fmt.Fprintf(&h.b, "%s: end list\n", location)
+	return nil
+}
+
+func (h *printHandler) Key(key []byte, location Location) error {
+	fmt.Fprintf(&h.b, "%s: key %q\n", location, key)
+	return nil
+}
+
+func (h *printHandler) String(value []byte, location Location) error {
+	fmt.Fprintf(&h.b, "%s: string %q\n", location, value)
+	return nil
+}
+
+func ExampleWalk() {
+	var h printHandler
+	err := Walk([]byte("{ fruit: orange tags: [ \"sweet fruit\" ] }"), &h)
+	fmt.Print(h.b.String())
+	fmt.Println(err)
+	// Output:
+	// 1:0: start dictionary
+	// 1:2: key "fruit"
+	// 1:9: string "orange"
+	// 1:16: key "tags"
+	// 1:22: start list
+	// 1:24: string "sweet fruit"
+	// 1:38: end list
+	// 1:40: end dictionary
+	// <nil>
+}
+
+// Print events of nodes the same way as printHandler does.
+func printNodeEvents(b *strings.Builder, node *Node) {
+	switch node.Kind {
+	case DictNode:
+		fmt.Fprintf(b, "%s: start dictionary\n", node.Location)
+		for _, e := range node.Entries {
+			fmt.Fprintf(b, "%s: key %q\n", e.Location, e.Key)
+			printNodeEvents(b, e.Value)
+		}
+	case ListNode:
+		fmt.Fprintf(b, "%s: start list\n", node.Location)
+		for _, item := range node.Items {
+			printNodeEvents(b, item)
+		}
+	case StringNode:
+		fmt.Fprintf(b, "%s: string %q\n", node.Location, node.Value)
+	}
+}
+
+// Drop end events which nodes have no locations for.
+func dropEndEvents(s string) string {
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(s, "\n") {
+		if !strings.Contains(line, ": end ") {
+			b.WriteString(line)
+		}
+	}
+	return b.String()
+}
+
+func TestWalk(t *testing.T) {
+	tests := []string{
+		"",
+		"a b # c\n c",
+		"{ a: [ b { c: \"d e\" } ] f: g\\ h }",
+		"[[[]]] {} x\\:y",
+		"{ a }",
+		"{ a: }",
+		"{ a: b ",
+		"[ a",
+		"{ a:b:c }",
+		"{ \"a\": b }",
+		"x: y",
+		"] a",
+		"a\\q",
+		"{ a: [ b } ]",
+		"[ { a: b ] }",
+		"[ a }",
+		"{ [ a",
+		"{ a: [ x: y } ]",
+	}
+	for _, test := range tests {
+		var h printHandler
+		err := Walk([]byte(test), &h)
+
+		nodes, nodeErr := Parse([]byte(test))
+		var b strings.Builder
+		for _, node := range nodes {
+			printNodeEvents(&b, node)
+		}
+		if fmt.Sprint(err) != fmt.Sprint(nodeErr) {
+			t.Errorf("%q: error = %v want %v", test, err, nodeErr)
+		}
+		if s := dropEndEvents(h.b.String()); err == nil && s != b.String() {
+			t.Errorf("%q: events =\n%s\nwant\n%s", test, s, b.String())
+		}
+	}
+}
+
+func TestWalk_Limits(t *testing.T) {
+	nested := func(n int) string {
+		return strings.Repeat("[", n) + strings.Repeat("]", n)
+	}
+	tests := []struct {
+		src     string
+		options ParserOptions
+		err     error
+	}{
+		{"{ a: [ b ] }", ParserOptions{MaxDepth: 2}, nil},
+		{"{ a: [ b ] }", ParserOptions{MaxDepth: 1}, ErrDepthLimit},
+		{"{ a: b c: d }", ParserOptions{MaxEntries: 1}, ErrEntryLimit},
+		{"[ a b ]", ParserOptions{MaxEntries: 1}, ErrEntryLimit},
+		{"a b c", ParserOptions{MaxEntries: 1}, nil},
+		{"{ abc: d }", ParserOptions{MaxStringLength: 2}, ErrStringLimit},
+		{"abc", ParserOptions{MaxStringLength: 2}, ErrStringLimit},
+		{"abc", ParserOptions{MaxInputSize: 2}, ErrInputLimit},
+		{"{ a_b: c }", ParserOptions{ExtendedKeys: true}, nil},
+		{"{ a_b: c }", ParserOptions{}, ErrInvalidKey},
+		{nested(DefaultMaxDepth + 1), ParserOptions{}, ErrDepthLimit},
+		{nested(100000), ParserOptions{MaxDepth: -1}, nil},
+	}
+	for _, test := range tests {
+		var h printHandler
+		if err := WalkWithOptions([]byte(test.src), &test.options, &h); !errors.Is(err, test.err) || err != nil && test.err == nil {
+			t.Errorf("%q %+v: error = %v want %v", test.src, test.options, err, test.err)
+		}
+	}
+}
+
+// Handler stopping at the first string.
+type stopHandler struct {
+	printHandler
+}
+
+var errStop = errors.New("stop")
+
+func (h *stopHandler) String(value []byte, location Location) error {
+	return errStop
+}
+
+func TestWalk_HandlerError(t *testing.T) {
+	var h stopHandler
+	if err := Walk([]byte("{ a: [ b ] }"), &h); err != errStop {
+		t.Errorf("error = %v want %v", err, errStop)
+	}
+	if s := h.b.String(); s != "1:0: start dictionary\n1:2: key \"a\"\n1:5: start list\n" {
+		t.Errorf("events = %q", s)
+	}
+}
+
+// Handler ignoring all events.
+type discardHandler struct{}
+
+func (discardHandler) StartDict(Location) error      { return nil }
+func (discardHandler) EndDict(Location) error        { return nil }
+func (discardHandler) StartList(Location) error      { return nil }
+func (discardHandler) EndList(Location) error        { return nil }
+func (discardHandler) Key([]byte, Location) error    { return nil }
+func (discardHandler) String([]byte, Location) error { return nil }
+
+// Benchmark walking a large flat dictionary.
+func BenchmarkWalk_Flat(b *testing.B) {
+	pot := []byte("{" + strings.Repeat(" key: \"some value\" list: [ a b c ]\n", 10000) + "}")
+	b.SetBytes(int64(len(pot)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := Walk(pot, discardHandler{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}